@@ -0,0 +1,202 @@
+package main
+
+import "fmt"
+
+// Data versions at which the way chunks are stored changed.
+const (
+	// paddedBlockStatesDataVersion is the 1.16 snapshot from which packed palette indexes no longer run over from one
+	// long into the next.
+	paddedBlockStatesDataVersion = 2527
+	// entitiesRemovedDataVersion is the 1.17 snapshot that moved entities out of chunks and into region files of their
+	// own, which anvil2slime does not read.
+	entitiesRemovedDataVersion = 2681
+	// levelRemovedDataVersion is the 1.18 snapshot that moved everything out of Level and into the root of the chunk,
+	// with sections that keep their blocks and biomes in palettes.
+	levelRemovedDataVersion = 2844
+)
+
+// MinecraftChunkRoot is a chunk as a region file stores it. Chunks before 1.18 keep their data in Level, and later
+// chunks keep it in the root.
+type MinecraftChunkRoot struct {
+	DataVersion int
+	Level       anvilLevel
+
+	X int `nbt:"xPos"`
+	Z int `nbt:"zPos"`
+	// MinSection is the lowest section of the world, which the named height maps count from.
+	MinSection    int `nbt:"yPos"`
+	Heightmaps    NBTCompound
+	Status        string
+	InhabitedTime int64
+	IsLightOn     byte                  `nbt:"isLightOn"`
+	Sections      []anvilPaletteSection `nbt:"sections"`
+	TileEntities  []NBTCompound         `nbt:"block_entities"`
+	BlockTicks    []NBTCompound         `nbt:"block_ticks"`
+	FluidTicks    []NBTCompound         `nbt:"fluid_ticks"`
+}
+
+// anvilLevel is the Level compound of chunks before 1.18.
+type anvilLevel struct {
+	X int `nbt:"xPos"`
+	Z int `nbt:"zPos"`
+
+	Entities     []NBTCompound
+	TileEntities []NBTCompound
+	TileTicks    []NBTCompound
+	LiquidTicks  []NBTCompound
+
+	// Biomes is a byte array before 1.13 and an int array after, with an entry for each cell from 1.15 on.
+	Biomes     interface{}
+	HeightMap  []int
+	Heightmaps NBTCompound
+
+	// Sections keep legacy block ids before 1.13 and a palette after.
+	Sections []MinecraftChunkSection
+
+	InhabitedTime    int64
+	Status           string
+	TerrainPopulated byte
+	LightPopulated   byte
+	IsLightOn        byte `nbt:"isLightOn"`
+}
+
+// anvilPaletteSection is a section of a chunk from 1.18 on.
+type anvilPaletteSection struct {
+	Y           int8
+	BlockStates anvilBlockPalette `nbt:"block_states"`
+	Biomes      anvilBiomePalette `nbt:"biomes"`
+	BlockLight  []byte
+	SkyLight    []byte
+}
+
+// anvilBlockPalette holds the blocks of a section from 1.18 on. Data is left out if the palette has a single entry.
+type anvilBlockPalette struct {
+	Palette []NBTCompound `nbt:"palette"`
+	Data    []int64       `nbt:"data"`
+}
+
+// anvilBiomePalette holds the biomes of the 64 cells of a section from 1.18 on. Data is left out if the palette has a
+// single entry.
+type anvilBiomePalette struct {
+	Palette []string `nbt:"palette"`
+	Data    []int64  `nbt:"data"`
+}
+
+// chunk converts a chunk to the form the rest of anvil2slime works with, and returns how many sections with blocks
+// outside the height of a Slime world it dropped. Sections and biomes are kept as they are stored, so they may be
+// empty or have the wrong size; tryToReadRegion checks them.
+func (root MinecraftChunkRoot) chunk(options AnvilReadOptions) (MinecraftChunk, int, error) {
+	if root.DataVersion >= levelRemovedDataVersion {
+		return root.paletteChunk(options)
+	}
+
+	level := root.Level
+	chunk := MinecraftChunk{
+		X:                level.X,
+		Z:                level.Z,
+		Entities:         level.Entities,
+		TileEntities:     level.TileEntities,
+		TileTicks:        level.TileTicks,
+		LiquidTicks:      level.LiquidTicks,
+		HeightMap:        level.HeightMap,
+		HeightMaps:       decodeHeightMaps(level.Heightmaps, root.DataVersion >= paddedBlockStatesDataVersion, 0),
+		InhabitedTime:    level.InhabitedTime,
+		Status:           level.Status,
+		TerrainPopulated: level.TerrainPopulated,
+		LightPopulated:   level.LightPopulated,
+	}
+	if level.IsLightOn != 0 || chunkStatusFinished(level.Status) {
+		chunk.LightPopulated = 1
+	}
+
+	switch biomes := level.Biomes.(type) {
+	case []byte:
+		chunk.Biomes = biomes
+	case []int32:
+		chunk.setBiomesFromIDs(biomes)
+	}
+
+	for _, section := range level.Sections {
+		if section.Y > 15 {
+			// 1.14 and later keep the light of the sections just below and above the world.
+			continue
+		}
+		if section.usesPalette() && root.DataVersion >= paddedBlockStatesDataVersion {
+			section.BlockStates = repackPaddedBlockStates(section.BlockStates, len(section.Palette))
+		}
+		chunk.Sections = append(chunk.Sections, section)
+	}
+	return chunk, 0, nil
+}
+
+// paletteChunk converts a chunk from 1.18 on. Entities are stored apart from these chunks and are not read. Sections
+// with blocks below y 0 or above y 255 fail the chunk unless options.ClipHeight drops them.
+func (root MinecraftChunkRoot) paletteChunk(options AnvilReadOptions) (MinecraftChunk, int, error) {
+	chunk := MinecraftChunk{
+		X:             root.X,
+		Z:             root.Z,
+		TileEntities:  root.TileEntities,
+		TileTicks:     root.BlockTicks,
+		LiquidTicks:   root.FluidTicks,
+		HeightMaps:    decodeHeightMaps(root.Heightmaps, true, root.MinSection*16),
+		InhabitedTime: root.InhabitedTime,
+		Status:        root.Status,
+	}
+	if root.IsLightOn != 0 || chunkStatusFinished(root.Status) {
+		chunk.LightPopulated = 1
+	}
+
+	biomes := make([]string, biomeCellsPerChunk)
+	clipped := 0
+	for _, stored := range root.Sections {
+		section := MinecraftChunkSection{
+			Y:          uint8(stored.Y),
+			BlockLight: stored.BlockLight,
+			SkyLight:   stored.SkyLight,
+			Palette:    stored.BlockStates.Palette,
+		}
+		if len(section.Palette) > 0 {
+			section.BlockStates = repackPaddedBlockStates(stored.BlockStates.Data, len(section.Palette))
+		}
+		if stored.Y < 0 || stored.Y > 15 {
+			if len(section.Palette) > 0 && !section.isEmpty() {
+				if !options.ClipHeight {
+					return chunk, 0, fmt.Errorf("section %d has blocks outside the 256 blocks a Slime world is high",
+						stored.Y)
+				}
+				clipped++
+			}
+			continue
+		}
+
+		stored.Biomes.unpack(biomes[int(stored.Y)*64 : (int(stored.Y)+1)*64])
+		if len(section.Palette) > 0 {
+			chunk.Sections = append(chunk.Sections, section)
+		}
+	}
+	chunk.setBiomesFromNames(biomes)
+	return chunk, clipped, nil
+}
+
+// unpack writes the biome of each cell of the section into cells, leaving them empty if the section has no biomes.
+func (p anvilBiomePalette) unpack(cells []string) {
+	if len(p.Palette) == 0 {
+		return
+	}
+	bits := 0
+	for 1<<uint(bits) < len(p.Palette) {
+		bits++
+	}
+	if bits == 0 {
+		for i := range cells {
+			cells[i] = p.Palette[0]
+		}
+		return
+	}
+	for i, idx := range unpackPaddedIndexes(p.Data, bits, len(cells)) {
+		if idx >= len(p.Palette) {
+			idx = 0
+		}
+		cells[i] = p.Palette[idx]
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/astei/anvil2slime/nbt"
+)
+
+// packPadded packs indexes the way 1.16 and later do.
+func packPadded(indexes []int, bits int) []int64 {
+	perLong := 64 / bits
+	packed := make([]int64, (len(indexes)+perLong-1)/perLong)
+	for i, idx := range indexes {
+		packed[i/perLong] |= int64(uint64(idx) << uint(i%perLong*bits))
+	}
+	return packed
+}
+
+func decodeChunk(t *testing.T, root map[string]interface{}) (MinecraftChunk, error) {
+	t.Helper()
+	chunk, _, err := decodeChunkRoot(t, root).chunk(AnvilReadOptions{})
+	return chunk, err
+}
+
+func decodeChunkRoot(t *testing.T, root map[string]interface{}) MinecraftChunkRoot {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := nbt.Marshal(&buf, root); err != nil {
+		t.Fatal(err)
+	}
+	var decoded MinecraftChunkRoot
+	if err := nbt.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func paletteOf(names ...string) []interface{} {
+	palette := make([]interface{}, len(names))
+	for i, name := range names {
+		palette[i] = map[string]interface{}{"Name": name}
+	}
+	return palette
+}
+
+func TestChunk_palettes(t *testing.T) {
+	blocks := make([]int, 4096)
+	blocks[0] = 1
+	cellBiomes := make([]int, 64)
+	for i := range cellBiomes {
+		if i&3 >= 2 {
+			cellBiomes[i] = 1
+		}
+	}
+	section := func(y int8, blockStates, biomes map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"Y": byte(y), "block_states": blockStates, "biomes": biomes}
+	}
+	root := map[string]interface{}{
+		"DataVersion":   int32(2975),
+		"xPos":          int32(3),
+		"zPos":          int32(-2),
+		"Status":        "minecraft:full",
+		"InhabitedTime": int64(40),
+		"block_ticks":   []interface{}{map[string]interface{}{"i": "minecraft:repeater", "x": int32(48), "y": int32(64)}},
+		"fluid_ticks":   []interface{}{map[string]interface{}{"i": "minecraft:water", "x": int32(49), "y": int32(-10)}},
+		"sections": []interface{}{
+			section(-1, map[string]interface{}{"palette": paletteOf("minecraft:air")},
+				map[string]interface{}{"palette": []string{"minecraft:deep_dark"}}),
+			section(0, map[string]interface{}{"palette": paletteOf("minecraft:stone")},
+				map[string]interface{}{"palette": []string{"minecraft:plains"}}),
+			section(4, map[string]interface{}{
+				"palette": paletteOf("minecraft:air", "minecraft:stone"),
+				"data":    packPadded(blocks, 4),
+			}, map[string]interface{}{
+				"palette": []string{"minecraft:windswept_hills", "mypack:crystal"},
+				"data":    packPadded(cellBiomes, 1),
+			}),
+		},
+	}
+
+	chunk, err := decodeChunk(t, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.X != 3 || chunk.Z != -2 || chunk.InhabitedTime != 40 || chunk.LightPopulated != 1 {
+		t.Errorf("unexpected chunk %d,%d with InhabitedTime %d and LightPopulated %d", chunk.X, chunk.Z,
+			chunk.InhabitedTime, chunk.LightPopulated)
+	}
+	if len(chunk.TileTicks) != 1 || len(chunk.LiquidTicks) != 1 || tickTarget(chunk.LiquidTicks[0]) != "minecraft:water" {
+		t.Errorf("expected a block tick and a water tick, got %v and %v", chunk.TileTicks, chunk.LiquidTicks)
+	}
+	if len(chunk.Sections) != 2 || chunk.Sections[0].Y != 0 || chunk.Sections[1].Y != 4 {
+		t.Fatalf("expected sections 0 and 4, got %+v", chunk.Sections)
+	}
+	if states := chunk.Sections[0].blockStates(); states[4095].Name != "minecraft:stone" {
+		t.Errorf("expected section 0 to be stone, got %s", states[4095])
+	}
+	if states := chunk.Sections[1].blockStates(); states[0].Name != "minecraft:stone" ||
+		states[1].Name != "minecraft:air" {
+		t.Errorf("expected stone and then air in section 4, got %s and %s", states[0], states[1])
+	}
+	if chunk.Biomes[0] != legacyBiomeIDs["minecraft:mountains"] || chunk.Biomes[15<<4|7] != chunk.Biomes[0] {
+		t.Errorf("expected windswept hills to be stored as mountains, got %d", chunk.Biomes[0])
+	}
+	if chunk.Biomes[8] != plainsBiome || chunk.CustomBiomes[8] != "mypack:crystal" ||
+		len(chunk.CustomBiomes) != 128 {
+		t.Errorf("expected half of the columns to keep mypack:crystal, got %d columns", len(chunk.CustomBiomes))
+	}
+	if chunk.CustomBiomeCells[biomeCellIndex(3, 8, 0)] != "mypack:crystal" || len(chunk.CustomBiomeCells) != 480 {
+		t.Errorf("expected the cells without biomes to take their column's, got %d mypack:crystal cells",
+			len(chunk.CustomBiomeCells))
+	}
+
+	root["sections"] = append(root["sections"].([]interface{}),
+		section(-2, map[string]interface{}{"palette": paletteOf("minecraft:deepslate")}, map[string]interface{}{}))
+	if _, err = decodeChunk(t, root); err == nil || !strings.Contains(err.Error(), "section -2") {
+		t.Errorf("expected an error for blocks below the world, got %v", err)
+	}
+	chunk, clipped, err := decodeChunkRoot(t, root).chunk(AnvilReadOptions{ClipHeight: true})
+	if err != nil || clipped != 1 || len(chunk.Sections) != 2 {
+		t.Errorf("expected the section below the world to be dropped, got %d sections, %d dropped (%v)",
+			len(chunk.Sections), clipped, err)
+	}
+}
+
+func TestChunk_level(t *testing.T) {
+	names := make([]string, 17)
+	for i := range names {
+		names[i] = fmt.Sprintf("minecraft:block_%d", i)
+	}
+	blocks := make([]int, 4096)
+	for i := range blocks {
+		blocks[i] = i % len(names)
+	}
+	biomes := make([]int32, biomeCellsPerChunk)
+	for i := range biomes {
+		biomes[i] = 1
+	}
+	biomes[biomeCellIndex(1, biomeSampleCellY, 0)] = 4
+	biomes[biomeCellIndex(2, biomeSampleCellY+1, 0)] = 5
+
+	palette := make([]NBTCompound, len(names))
+	for i, name := range names {
+		palette[i] = NBTCompound{"Name": name}
+	}
+	chunk, _, err := MinecraftChunkRoot{
+		DataVersion: 2586,
+		Level: anvilLevel{
+			Biomes:   biomes,
+			Status:   "full",
+			Sections: []MinecraftChunkSection{{Y: 255}, {Y: 2, Palette: palette, BlockStates: packPadded(blocks, 5)}},
+		},
+	}.chunk(AnvilReadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk.Sections) != 1 {
+		t.Fatalf("expected the section below the world to be left out, got %d sections", len(chunk.Sections))
+	}
+	for i, state := range chunk.Sections[0].blockStates() {
+		if state.Name != names[i%len(names)] {
+			t.Fatalf("block %d: expected %s, got %s", i, names[i%len(names)], state)
+		}
+	}
+	for column, expected := range map[int]byte{0: 1, 4: 4, 3<<4 | 7: 4, 8: 1, 15<<4 | 15: 1} {
+		if chunk.Biomes[column] != expected {
+			t.Errorf("column %d: expected biome %d, got %d", column, expected, chunk.Biomes[column])
+		}
+	}
+	if len(chunk.BiomeCells) != biomeCellsPerChunk || chunk.BiomeCells[biomeCellIndex(2, biomeSampleCellY+1, 0)] != 5 {
+		t.Errorf("expected the biome of every cell to be kept, got %d cells", len(chunk.BiomeCells))
+	}
+}
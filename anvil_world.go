@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,19 +14,43 @@ import (
 var blank [4096]byte
 
 type ChunkCoord struct {
-	X int
-	Z int
+	X int `json:"x"`
+	Z int `json:"z"`
 }
 
 type AnvilWorld struct {
 	chunks map[ChunkCoord]MinecraftChunk
 }
 
-func OpenAnvilWorld(root string) (world *AnvilWorld, err error) {
+// AnvilReadOptions controls how OpenAnvilWorld reads a world. The zero value uses the default settings.
+type AnvilReadOptions struct {
+	// ClipHeight drops the sections of chunks from 1.18 on that have blocks below y 0 or above y 255, which a Slime
+	// world cannot hold, instead of failing their region.
+	ClipHeight bool
+}
+
+// regionResult holds the chunks of a region and counts what reading them left out.
+type regionResult struct {
+	chunks map[ChunkCoord]MinecraftChunk
+	// clippedSections is how many sections ClipHeight dropped.
+	clippedSections int
+	// entityChunks is how many chunks keep their entities in the separate region files of 1.17 and later.
+	entityChunks int
+}
+
+// OpenAnvilWorld loads every region in the root directory concurrently, reporting its progress to progress (which
+// may be nil). Loading stops early and returns the context's error if ctx is cancelled.
+func OpenAnvilWorld(ctx context.Context, root string, options AnvilReadOptions,
+	progress ProgressReporter) (world *AnvilWorld, err error) {
+	if progress == nil {
+		progress = noopProgressReporter{}
+	}
+
 	rootDirectory, err := os.Open(root)
 	if err != nil {
 		return
 	}
+	defer rootDirectory.Close()
 
 	files, err := rootDirectory.Readdir(0)
 	if err != nil {
@@ -35,52 +59,87 @@ func OpenAnvilWorld(root string) (world *AnvilWorld, err error) {
 
 	var regionReaders []*AnvilReader
 	for _, possibleRegionFile := range files {
-		fmt.Println("discovered ", possibleRegionFile.Name())
-		if strings.HasSuffix(possibleRegionFile.Name(), ".mca") {
-			file, err := os.Open(filepath.Join(root, possibleRegionFile.Name()))
-			if err != nil {
-				return nil, err
-			}
-			reader, err := NewAnvilReader(file)
-			if err != nil {
-				return nil, err
-			}
-			regionReaders = append(regionReaders, reader)
+		if !strings.HasSuffix(possibleRegionFile.Name(), ".mca") {
+			logger.Debug("skipping non-region file", "file", possibleRegionFile.Name())
+			continue
+		}
+		logger.Debug("discovered region", "region", possibleRegionFile.Name())
+		file, err := os.Open(filepath.Join(root, possibleRegionFile.Name()))
+		if err != nil {
+			closeAnvilReaders(regionReaders)
+			return nil, err
+		}
+		reader, err := NewAnvilReader(file)
+		if err != nil {
+			_ = file.Close()
+			closeAnvilReaders(regionReaders)
+			return nil, err
 		}
+		regionReaders = append(regionReaders, reader)
 	}
+	defer closeAnvilReaders(regionReaders)
+
+	progress.Begin(len(regionReaders))
 
 	var wg sync.WaitGroup
 	wg.Add(len(regionReaders))
-	resultChan := make(chan *map[ChunkCoord]MinecraftChunk, len(regionReaders))
+	resultChan := make(chan *regionResult, len(regionReaders))
 	for _, reader := range regionReaders {
-		go func(reader *AnvilReader, res chan *map[ChunkCoord]MinecraftChunk, wg *sync.WaitGroup) {
+		go func(reader *AnvilReader, res chan *regionResult, wg *sync.WaitGroup) {
 			defer wg.Done()
-			result, err := tryToReadRegion(reader)
+			result, err := tryToReadRegion(ctx, reader, options, progress)
 			if err != nil {
-				fmt.Println("Unable to read chunks: " + err.Error())
+				if ctx.Err() == nil {
+					logger.Error("unable to read chunks", "region", reader.Name, "error", err)
+				}
 				return
 			}
+			progress.RegionLoaded(reader.Name, len(result.chunks))
 			res <- result
 		}(reader, resultChan, &wg)
 	}
 
 	wg.Wait()
 	close(resultChan)
+	progress.End()
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	allChunks := make(map[ChunkCoord]MinecraftChunk)
-	for m := range resultChan {
-		for k, v := range *m {
+	clippedSections, entityChunks := 0, 0
+	for result := range resultChan {
+		for k, v := range result.chunks {
 			allChunks[k] = v
 		}
+		clippedSections += result.clippedSections
+		entityChunks += result.entityChunks
+	}
+	logger.Info("discovered chunks in the world", "chunks", len(allChunks))
+	if clippedSections > 0 {
+		logger.Warn("dropped sections outside the 256 blocks a Slime world is high", "sections", clippedSections)
+	}
+	if entityChunks > 0 {
+		logger.Warn("chunks from 1.17 on keep their entities apart from them, and those were not read",
+			"chunks", entityChunks)
 	}
-	fmt.Printf("Discovered %d chunks in the world\n", len(allChunks))
 	return &AnvilWorld{chunks: allChunks}, nil
 }
 
-func tryToReadRegion(reader *AnvilReader) (*map[ChunkCoord]MinecraftChunk, error) {
-	byXZ := make(map[ChunkCoord]MinecraftChunk)
+func closeAnvilReaders(readers []*AnvilReader) {
+	for _, reader := range readers {
+		_ = reader.Close()
+	}
+}
+
+func tryToReadRegion(ctx context.Context, reader *AnvilReader, options AnvilReadOptions,
+	progress ProgressReporter) (*regionResult, error) {
+	result := &regionResult{chunks: make(map[ChunkCoord]MinecraftChunk)}
 	for x := 0; x < 32; x++ {
 		for z := 0; z < 32; z++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			if reader.ChunkExists(x, z) {
 				chunkReader, err := reader.ReadChunk(x, z)
 				if err != nil {
@@ -91,44 +150,62 @@ func tryToReadRegion(reader *AnvilReader) (*map[ChunkCoord]MinecraftChunk, error
 				if err = nbt.NewDecoder(chunkReader).Decode(&anvilChunkRoot); err != nil {
 					return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: %s", x, z, reader.Name, err.Error())
 				}
+				chunk, clipped, err := anvilChunkRoot.chunk(options)
+				if err != nil {
+					return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: %s", x, z, reader.Name, err.Error())
+				}
+				result.clippedSections += clipped
 
 				var cleanedSections []MinecraftChunkSection
-				for _, section := range anvilChunkRoot.Level.Sections {
-					if !bytes.Equal(blank[:], section.Blocks) {
-						cleanedSections = append(cleanedSections, section)
-
+				for _, section := range chunk.Sections {
+					if section.isEmpty() {
+						continue
+					}
+					if section.usesPalette() {
+						if len(section.BlockStates) != 64*paletteBitsPerBlock(len(section.Palette)) {
+							return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: invalid block states size", x, z, reader.Name)
+						}
+					} else {
 						if len(section.Blocks) != 4096 {
 							return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: invalid blocks size", x, z, reader.Name)
 						}
-						if len(section.BlockLight) != 2048 {
-							return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: invalid block light size", x, z, reader.Name)
-						}
-						if len(section.SkyLight) != 2048 {
-							return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: invalid sky light size", x, z, reader.Name)
-						}
 						if len(section.Data) != 2048 {
 							return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: invalid block data size", x, z, reader.Name)
 						}
-
 					}
+					if len(section.BlockLight) != 2048 || len(section.SkyLight) != 2048 {
+						// Treat the light as missing. It is written as darkness unless it is recomputed.
+						logger.Debug("chunk has invalid light", "region", reader.Name, "x", x, "z", z)
+						section.BlockLight, section.SkyLight = nil, nil
+						chunk.LightPopulated = 0
+					}
+					cleanedSections = append(cleanedSections, section)
 				}
-				anvilChunkRoot.Level.Sections = cleanedSections
-				if len(anvilChunkRoot.Level.Sections) == 0 {
+				chunk.Sections = cleanedSections
+				if len(chunk.Sections) == 0 {
 					continue
 				}
 
 				// further sanity checks...
-				if len(anvilChunkRoot.Level.HeightMap) != 256 {
-					return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: invalid height map size", x, z, reader.Name)
+				if len(chunk.HeightMap) != 256 {
+					// The height map is recomputed from the blocks when the world is written. Chunks from 1.13 on
+					// have named height maps instead.
+					if len(chunk.HeightMaps) == 0 {
+						logger.Debug("chunk has invalid height map", "region", reader.Name, "x", x, "z", z)
+					}
+					chunk.HeightMap = nil
 				}
-				if len(anvilChunkRoot.Level.Biomes) != 256 {
+				if len(chunk.Biomes) != 256 {
 					return nil, fmt.Errorf("could not deserialize chunk %d,%d in %s: invalid biome size", x, z, reader.Name)
 				}
 
-				coords := ChunkCoord{X: anvilChunkRoot.Level.X, Z: anvilChunkRoot.Level.Z}
-				byXZ[coords] = anvilChunkRoot.Level
+				result.chunks[ChunkCoord{X: chunk.X, Z: chunk.Z}] = chunk
+				if anvilChunkRoot.DataVersion >= entitiesRemovedDataVersion {
+					result.entityChunks++
+				}
+				progress.ChunkLoaded()
 			}
 		}
 	}
-	return &byXZ, nil
+	return result, nil
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var ErrOutputExists = errors.New("output file already exists")
+
+// atomicFile is a file that is written under a temporary name in the same directory as its destination and only
+// moved into place by Commit. A crash or error before then leaves any existing file at the destination untouched.
+type atomicFile struct {
+	*os.File
+	destination string
+	noClobber   bool
+	done        bool
+}
+
+// createAtomicFile creates a temporary file next to destination. If noClobber is set, the file will not replace an
+// existing file at the destination.
+func createAtomicFile(destination string, noClobber bool) (*atomicFile, error) {
+	if noClobber {
+		if _, err := os.Lstat(destination); err == nil {
+			return nil, fmt.Errorf("%s: %w", destination, ErrOutputExists)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	dir, base := filepath.Split(destination)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := ioutil.TempFile(dir, "."+base+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: file, destination: destination, noClobber: noClobber}, nil
+}
+
+// Commit flushes the temporary file to disk and moves it to its destination.
+func (f *atomicFile) Commit() (err error) {
+	if f.done {
+		return errors.New("atomic file already committed or aborted")
+	}
+	f.done = true
+	tempName := f.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tempName)
+		}
+	}()
+
+	if err = f.Chmod(0644); err != nil {
+		_ = f.Close()
+		return
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	if f.noClobber {
+		// Linking fails if the destination exists, so it cannot race with another writer the way a check followed
+		// by a rename could.
+		if err = os.Link(tempName, f.destination); err != nil {
+			if os.IsExist(err) {
+				err = fmt.Errorf("%s: %w", f.destination, ErrOutputExists)
+			}
+			return
+		}
+		_ = os.Remove(tempName)
+	} else if err = os.Rename(tempName, f.destination); err != nil {
+		return
+	}
+
+	syncDirectory(filepath.Dir(f.destination))
+	return nil
+}
+
+// Abort closes and removes the temporary file. It is safe to call after Commit, in which case it does nothing.
+func (f *atomicFile) Abort() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// syncDirectory makes a rename in dir durable. Not every platform supports syncing a directory, so failures are
+// ignored.
+func syncDirectory(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"text/tabwriter"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var benchmarkedCompressionLevels = []zstd.EncoderLevel{
+	zstd.SpeedFastest,
+	zstd.SpeedDefault,
+	zstd.SpeedBetterCompression,
+	zstd.SpeedBestCompression,
+}
+
+type CompressionBenchmark struct {
+	Level    zstd.EncoderLevel
+	Size     int64
+	Duration time.Duration
+}
+
+// BenchmarkCompression writes the world once for each compression level and reports the size of the file and the time
+// it took to write. Every other setting is taken from options.
+func (world *AnvilWorld) BenchmarkCompression(ctx context.Context, options SlimeWriteOptions) ([]CompressionBenchmark, error) {
+	var results []CompressionBenchmark
+	for _, level := range benchmarkedCompressionLevels {
+		options.CompressionLevel = level
+		counter := &countingWriter{writer: ioutil.Discard}
+
+		start := time.Now()
+		if err := world.WriteAsSlime(ctx, counter, options); err != nil {
+			return nil, err
+		}
+		results = append(results, CompressionBenchmark{Level: level, Size: counter.n, Duration: time.Since(start)})
+	}
+	return results, nil
+}
+
+func printCompressionBenchmarks(out io.Writer, results []CompressionBenchmark) error {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(table, "LEVEL\tSIZE (BYTES)\tTIME (MS)\t")
+	for _, result := range results {
+		_, _ = fmt.Fprintf(table, "%s\t%d\t%d\t\n", result.Level, result.Size, result.Duration.Milliseconds())
+	}
+	return table.Flush()
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	writer io.Writer
+	n      int64
+}
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = w.writer.Write(p)
+	w.n += int64(n)
+	return
+}
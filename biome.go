@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// legacyBiomeNames maps the numeric biome ids that chunks store in their biome arrays to the names 1.13 gave them.
+var legacyBiomeNames = map[byte]string{
+	0: "ocean", 1: "plains", 2: "desert", 3: "mountains", 4: "forest", 5: "taiga", 6: "swamp", 7: "river",
+	8: "nether", 9: "the_end", 10: "frozen_ocean", 11: "frozen_river", 12: "snowy_tundra", 13: "snowy_mountains",
+	14: "mushroom_fields", 15: "mushroom_field_shore", 16: "beach", 17: "desert_hills", 18: "wooded_hills",
+	19: "taiga_hills", 20: "mountain_edge", 21: "jungle", 22: "jungle_hills", 23: "jungle_edge", 24: "deep_ocean",
+	25: "stone_shore", 26: "snowy_beach", 27: "birch_forest", 28: "birch_forest_hills", 29: "dark_forest",
+	30: "snowy_taiga", 31: "snowy_taiga_hills", 32: "giant_tree_taiga", 33: "giant_tree_taiga_hills",
+	34: "wooded_mountains", 35: "savanna", 36: "savanna_plateau", 37: "badlands", 38: "wooded_badlands_plateau",
+	39: "badlands_plateau", 40: "small_end_islands", 41: "end_midlands", 42: "end_highlands", 43: "end_barrens",
+	44: "warm_ocean", 45: "lukewarm_ocean", 46: "cold_ocean", 47: "deep_warm_ocean", 48: "deep_lukewarm_ocean",
+	49: "deep_cold_ocean", 50: "deep_frozen_ocean", 127: "the_void", 129: "sunflower_plains", 130: "desert_lakes",
+	131: "gravelly_mountains", 132: "flower_forest", 133: "taiga_mountains", 134: "swamp_hills", 140: "ice_spikes",
+	149: "modified_jungle", 151: "modified_jungle_edge", 155: "tall_birch_forest", 156: "tall_birch_hills",
+	157: "dark_forest_hills", 158: "snowy_taiga_mountains", 160: "giant_spruce_taiga",
+	161: "giant_spruce_taiga_hills", 162: "modified_gravelly_mountains", 163: "shattered_savanna",
+	164: "shattered_savanna_plateau", 165: "eroded_badlands", 166: "modified_wooded_badlands_plateau",
+	167: "modified_badlands_plateau", 168: "bamboo_jungle", 169: "bamboo_jungle_hills", 170: "soul_sand_valley",
+	171: "crimson_forest", 172: "warped_forest", 173: "basalt_deltas",
+}
+
+// renamedBiomes maps the names 1.18 gave biomes that already existed to the names 1.13 gave them, so that they keep
+// their numeric ids.
+var renamedBiomes = map[string]string{
+	"minecraft:old_growth_birch_forest":  "minecraft:tall_birch_forest",
+	"minecraft:old_growth_pine_taiga":    "minecraft:giant_tree_taiga",
+	"minecraft:old_growth_spruce_taiga":  "minecraft:giant_spruce_taiga",
+	"minecraft:snowy_plains":             "minecraft:snowy_tundra",
+	"minecraft:sparse_jungle":            "minecraft:jungle_edge",
+	"minecraft:stony_shore":              "minecraft:stone_shore",
+	"minecraft:windswept_forest":         "minecraft:wooded_mountains",
+	"minecraft:windswept_gravelly_hills": "minecraft:gravelly_mountains",
+	"minecraft:windswept_hills":          "minecraft:mountains",
+	"minecraft:windswept_savanna":        "minecraft:shattered_savanna",
+	"minecraft:wooded_badlands":          "minecraft:wooded_badlands_plateau",
+}
+
+// plainsBiome is the biome of columns whose biome is unknown, as it is for the game.
+const plainsBiome = 1
+
+var legacyBiomeIDs = make(map[string]byte, len(legacyBiomeNames))
+
+func init() {
+	for id, name := range legacyBiomeNames {
+		legacyBiomeIDs["minecraft:"+name] = id
+	}
+}
+
+var ErrUnknownBiome = errors.New("unknown biome")
+
+// parseBiome accepts a numeric biome id or a biome name, with the namespace defaulting to minecraft, and returns the
+// namespaced name.
+func parseBiome(text string) (string, error) {
+	if id, err := strconv.Atoi(text); err == nil {
+		if id >= 0 && id < 256 {
+			if name, ok := legacyBiomeNames[byte(id)]; ok {
+				return "minecraft:" + name, nil
+			}
+		}
+		return "", fmt.Errorf("%w %d", ErrUnknownBiome, id)
+	}
+	if text == "" {
+		return "", fmt.Errorf("%w: missing name", ErrUnknownBiome)
+	}
+	if !strings.Contains(text, ":") {
+		text = "minecraft:" + text
+	}
+	if legacyName, ok := renamedBiomes[text]; ok {
+		text = legacyName
+	}
+	return text, nil
+}
+
+// biomeID returns the numeric id of a namespaced biome name, or plains and false for biomes that have none, such as
+// biomes added after 1.16 or by data packs.
+func biomeID(name string) (byte, bool) {
+	if legacyName, ok := renamedBiomes[name]; ok {
+		name = legacyName
+	}
+	if id, ok := legacyBiomeIDs[name]; ok {
+		return id, true
+	}
+	return plainsBiome, false
+}
+
+// Chunks from 1.15 on store a biome for each 4x4x4 cell rather than for each column. The chunk header of a Slime world
+// stores one biome per column, which is taken from the cell at sea level, and the extra data keeps the cells.
+const (
+	biomeCellsPerChunk = 1024
+	biomeSampleCellY   = 64 >> 2
+)
+
+// biomeCellIndex returns where a cell is in the biomes of a chunk from 1.15 on, in YZX order.
+func biomeCellIndex(x, y, z int) int {
+	return y<<4 | z<<2 | x
+}
+
+// sampleBiomeCell returns the cell whose biome the columns of a 4x4 cell column take: the one at sea level, or the
+// nearest one above or below it that has a biome. It returns -1 if none of them has one.
+func sampleBiomeCell(hasBiome func(i int) bool, x, z int) int {
+	for distance := 0; distance < biomeCellsPerChunk>>4; distance++ {
+		for _, y := range []int{biomeSampleCellY + distance, biomeSampleCellY - distance - 1} {
+			if y >= 0 && y < biomeCellsPerChunk>>4 && hasBiome(biomeCellIndex(x, y, z)) {
+				return biomeCellIndex(x, y, z)
+			}
+		}
+	}
+	return -1
+}
+
+// setBiomeColumns sets the biome of each of the 16 columns a 4x4 cell column covers.
+func (chunk *MinecraftChunk) setBiomeColumns(x, z int, id byte, customName string) {
+	for columnZ := z << 2; columnZ < z<<2+4; columnZ++ {
+		for columnX := x << 2; columnX < x<<2+4; columnX++ {
+			i := columnZ<<4 | columnX
+			chunk.Biomes[i] = id
+			if customName != "" {
+				if chunk.CustomBiomes == nil {
+					chunk.CustomBiomes = make(map[int]string)
+				}
+				chunk.CustomBiomes[i] = customName
+			}
+		}
+	}
+}
+
+// setBiomesFromIDs sets the biomes of a chunk from numeric ids, one for each column as 1.13 and 1.14 store them, or
+// one for each cell as 1.15 through 1.17 do. Ids that do not fit in a byte are stored as plains. The chunk is left
+// without biomes if there are neither as many ids as columns nor as cells.
+func (chunk *MinecraftChunk) setBiomesFromIDs(ids []int32) {
+	toByte := func(id int32) byte {
+		if id < 0 || id > 255 {
+			return plainsBiome
+		}
+		return byte(id)
+	}
+	switch len(ids) {
+	case 256:
+		chunk.Biomes = make([]byte, 256)
+		for i, id := range ids {
+			chunk.Biomes[i] = toByte(id)
+		}
+	case biomeCellsPerChunk:
+		chunk.BiomeCells = make([]byte, biomeCellsPerChunk)
+		for i, id := range ids {
+			chunk.BiomeCells[i] = toByte(id)
+		}
+		chunk.setBiomesFromCells()
+	}
+}
+
+// setBiomesFromNames sets the biomes of a chunk from the name of the biome of each cell, as 1.18 and later store
+// them, with an empty name for cells that have none. Those take the biome their columns take, or plains. Biomes that
+// have no numeric id are kept in CustomBiomeCells and CustomBiomes.
+func (chunk *MinecraftChunk) setBiomesFromNames(cells []string) {
+	chunk.BiomeCells = make([]byte, biomeCellsPerChunk)
+	chunk.CustomBiomeCells = nil
+	for z := 0; z < 4; z++ {
+		for x := 0; x < 4; x++ {
+			columnBiome := ""
+			if cell := sampleBiomeCell(func(i int) bool { return cells[i] != "" }, x, z); cell >= 0 {
+				columnBiome = cells[cell]
+			}
+			for y := 0; y < biomeCellsPerChunk>>4; y++ {
+				i := biomeCellIndex(x, y, z)
+				name := cells[i]
+				if name == "" {
+					name = columnBiome
+				}
+				id, known := biomeID(name)
+				chunk.BiomeCells[i] = id
+				if !known && name != "" {
+					if chunk.CustomBiomeCells == nil {
+						chunk.CustomBiomeCells = make(map[int]string)
+					}
+					chunk.CustomBiomeCells[i] = name
+				}
+			}
+		}
+	}
+	chunk.setBiomesFromCells()
+}
+
+// setBiomesFromCells sets the biome of every column from BiomeCells and CustomBiomeCells.
+func (chunk *MinecraftChunk) setBiomesFromCells() {
+	chunk.Biomes = make([]byte, 256)
+	chunk.CustomBiomes = nil
+	for z := 0; z < 4; z++ {
+		for x := 0; x < 4; x++ {
+			cell := biomeCellIndex(x, biomeSampleCellY, z)
+			chunk.setBiomeColumns(x, z, chunk.BiomeCells[cell], chunk.CustomBiomeCells[cell])
+		}
+	}
+}
+
+// BiomeRemap replaces biomes by name. Biomes stored as numeric ids are looked up by the names 1.13 gave them.
+type BiomeRemap struct {
+	// Uniform, if set, replaces every biome, taking precedence over Mapping.
+	Uniform string
+	// Mapping replaces the biomes it has a key for with the value.
+	Mapping map[string]string
+}
+
+// ParseBiomeMapping reads one FROM -> TO mapping per line, where each side is a biome name or numeric id. Blank lines
+// and lines starting with # are ignored.
+func ParseBiomeMapping(reader io.Reader) (map[string]string, error) {
+	mapping := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Split(text, "->")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: invalid mapping %q (expected FROM -> TO)", line, text)
+		}
+		from, err := parseBiome(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		to, err := parseBiome(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		mapping[from] = to
+	}
+	return mapping, scanner.Err()
+}
+
+func (r BiomeRemap) empty() bool {
+	return r.Uniform == "" && len(r.Mapping) == 0
+}
+
+// remap returns the biome that replaces the given one, or the same biome if it stays.
+func (r BiomeRemap) remap(name string) string {
+	if r.Uniform != "" {
+		return r.Uniform
+	}
+	if to, ok := r.Mapping[name]; ok {
+		return to
+	}
+	return name
+}
+
+// remapColumn returns the biome that replaces the biome of a column, which is given by its numeric id and, for biomes
+// without one, its name. Biomes without a numeric id are returned as plains and their name.
+func (r BiomeRemap) remapColumn(id byte, customName string) (byte, string) {
+	name := customName
+	if name == "" {
+		legacyName, known := legacyBiomeNames[id]
+		if !known && r.Uniform == "" {
+			return id, ""
+		}
+		name = "minecraft:" + legacyName
+	}
+	to := r.remap(name)
+	if to == name {
+		return id, customName
+	}
+	if toID, known := biomeID(to); known {
+		return toID, ""
+	}
+	return plainsBiome, to
+}
+
+// RemapBiomes replaces the biomes of every column and cell of every chunk and returns how many columns changed. Biomes
+// without a numeric id, such as those of data packs, are remapped like any other, both from and to, and kept in
+// CustomBiomes and CustomBiomeCells.
+func (world *AnvilWorld) RemapBiomes(remap BiomeRemap) int {
+	var lookup [256]struct {
+		id         byte
+		customName string
+	}
+	for id := range lookup {
+		lookup[id].id, lookup[id].customName = remap.remapColumn(byte(id), "")
+	}
+
+	remapAll := func(ids []byte, customNames map[int]string) ([]byte, map[int]string, int) {
+		remapped := make([]byte, len(ids))
+		var remappedNames map[int]string
+		changed := 0
+		for i, id := range ids {
+			name, custom := customNames[i]
+			var customName string
+			if custom {
+				remapped[i], customName = remap.remapColumn(id, name)
+			} else {
+				remapped[i], customName = lookup[id].id, lookup[id].customName
+			}
+			if remapped[i] != id || customName != name {
+				changed++
+			}
+			if customName != "" {
+				if remappedNames == nil {
+					remappedNames = make(map[int]string)
+				}
+				remappedNames[i] = customName
+			}
+		}
+		return remapped, remappedNames, changed
+	}
+
+	changed := 0
+	for coord, chunk := range world.chunks {
+		var columns int
+		chunk.Biomes, chunk.CustomBiomes, columns = remapAll(chunk.Biomes, chunk.CustomBiomes)
+		changed += columns
+		if chunk.BiomeCells != nil {
+			chunk.BiomeCells, chunk.CustomBiomeCells, _ = remapAll(chunk.BiomeCells, chunk.CustomBiomeCells)
+		}
+		world.chunks[coord] = chunk
+	}
+	return changed
+}
+
+// extraBiomes returns the biomes of a chunk that the chunk header of a Slime file cannot store, for the extra data:
+// the names of biomes without a numeric id by column in ZX order, left empty for the others, and the biome of every
+// cell in the 1024-entry layout of 1.15, with names for cells the same way. It returns nil if the header holds them
+// all.
+func (chunk MinecraftChunk) extraBiomes() NBTCompound {
+	biomes := make(NBTCompound)
+	if len(chunk.CustomBiomes) > 0 {
+		biomes["Custom"] = biomeNames(chunk.CustomBiomes, 256)
+	}
+	if chunk.BiomeCells != nil {
+		cells := make([]int32, len(chunk.BiomeCells))
+		for i, id := range chunk.BiomeCells {
+			cells[i] = int32(id)
+		}
+		biomes["Cells"] = cells
+		if len(chunk.CustomBiomeCells) > 0 {
+			biomes["CustomCells"] = biomeNames(chunk.CustomBiomeCells, len(cells))
+		}
+	}
+	if len(biomes) == 0 {
+		return nil
+	}
+	biomes["x"], biomes["z"] = int32(chunk.X), int32(chunk.Z)
+	return biomes
+}
+
+// setExtraBiomes sets the biomes of a chunk that extraBiomes returned.
+func (chunk *MinecraftChunk) setExtraBiomes(custom []string, cells []int32, customCells []string) {
+	chunk.CustomBiomes = biomesByIndex(custom)
+	chunk.BiomeCells, chunk.CustomBiomeCells = nil, nil
+	if len(cells) == biomeCellsPerChunk {
+		chunk.BiomeCells = make([]byte, len(cells))
+		for i, id := range cells {
+			chunk.BiomeCells[i] = byte(id)
+		}
+		chunk.CustomBiomeCells = biomesByIndex(customCells)
+	}
+}
+
+// biomeNames lays names out by index, leaving the entries without a name empty.
+func biomeNames(byIndex map[int]string, length int) []string {
+	names := make([]string, length)
+	for i, name := range byIndex {
+		if i >= 0 && i < length {
+			names[i] = name
+		}
+	}
+	return names
+}
+
+// biomesByIndex is the reverse of biomeNames.
+func biomesByIndex(names []string) map[int]string {
+	var byIndex map[int]string
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		if byIndex == nil {
+			byIndex = make(map[int]string)
+		}
+		byIndex[i] = name
+	}
+	return byIndex
+}
+
+// customBiomeColumns returns how many columns have a biome without a numeric id, which Slime files store as plains.
+func (world *AnvilWorld) customBiomeColumns() int {
+	columns := 0
+	for _, chunk := range world.chunks {
+		columns += len(chunk.CustomBiomes)
+	}
+	return columns
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBiomeMapping(t *testing.T) {
+	mapping, err := ParseBiomeMapping(strings.NewReader(`
+# dry everything out
+ocean -> desert
+7 -> minecraft:plains
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping["minecraft:ocean"] != "minecraft:desert" || mapping["minecraft:river"] != "minecraft:plains" {
+		t.Errorf("unexpected mapping %v", mapping)
+	}
+
+	if mapping, err = ParseBiomeMapping(strings.NewReader("windswept_hills -> plains")); err != nil ||
+		mapping["minecraft:mountains"] != "minecraft:plains" {
+		t.Errorf("expected the 1.18 name to be mapped by its 1.13 name, got %v (%v)", mapping, err)
+	}
+
+	for _, invalid := range []string{"ocean", "ocean -> ", "300 -> plains", "ocean -> 52"} {
+		if _, err = ParseBiomeMapping(strings.NewReader(invalid)); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestRemapBiomes(t *testing.T) {
+	newWorld := func() *AnvilWorld {
+		biomes := make([]byte, 256)
+		for i := range biomes {
+			biomes[i] = byte(i % 3)
+		}
+		return &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{}: {Biomes: biomes}}}
+	}
+
+	world := newWorld()
+	changed := world.RemapBiomes(BiomeRemap{Mapping: map[string]string{"minecraft:ocean": "minecraft:forest"}})
+	biomes := world.chunks[ChunkCoord{}].Biomes
+	if changed != 86 || biomes[0] != 4 || biomes[1] != 1 || biomes[2] != 2 {
+		t.Errorf("unexpected result: %d changed, biomes start with %v", changed, biomes[:3])
+	}
+
+	world = newWorld()
+	changed = world.RemapBiomes(BiomeRemap{Uniform: "minecraft:plains"})
+	for _, biome := range world.chunks[ChunkCoord{}].Biomes {
+		if biome != 1 {
+			t.Fatalf("expected every biome to be plains, got %d", biome)
+		}
+	}
+	if changed != 171 {
+		t.Errorf("expected 171 columns to change, got %d", changed)
+	}
+
+	world = newWorld()
+	chunk := world.chunks[ChunkCoord{}]
+	chunk.CustomBiomes = map[int]string{1: "mypack:crystal", 4: "mypack:lobby"}
+	world.chunks[ChunkCoord{}] = chunk
+	changed = world.RemapBiomes(BiomeRemap{Mapping: map[string]string{
+		"minecraft:plains":    "minecraft:desert",
+		"mypack:crystal":      "minecraft:mountains",
+		"minecraft:mountains": "minecraft:forest",
+	}})
+	chunk = world.chunks[ChunkCoord{}]
+	if chunk.Biomes[1] != 3 || chunk.Biomes[4] != 1 || chunk.Biomes[7] != 2 || chunk.CustomBiomes[4] != "mypack:lobby" ||
+		len(chunk.CustomBiomes) != 1 {
+		t.Errorf("unexpected biomes %v with custom biomes %v", chunk.Biomes[:8], chunk.CustomBiomes)
+	}
+	if changed != 84 {
+		t.Errorf("expected 84 columns to change, got %d", changed)
+	}
+
+	world = newWorld()
+	changed = world.RemapBiomes(BiomeRemap{Mapping: map[string]string{
+		"minecraft:ocean":  "mypack:lobby",
+		"minecraft:plains": "minecraft:cherry_grove",
+	}})
+	chunk = world.chunks[ChunkCoord{}]
+	if chunk.Biomes[0] != 1 || chunk.CustomBiomes[0] != "mypack:lobby" || chunk.Biomes[1] != 1 ||
+		chunk.CustomBiomes[1] != "minecraft:cherry_grove" || chunk.Biomes[2] != 2 || len(chunk.CustomBiomes) != 171 {
+		t.Errorf("unexpected biomes %v with %d custom biomes", chunk.Biomes[:3], len(chunk.CustomBiomes))
+	}
+	if changed != 171 {
+		t.Errorf("expected 171 columns to change, got %d", changed)
+	}
+
+	world = newWorld()
+	world.RemapBiomes(BiomeRemap{Uniform: "custom:lobby"})
+	if chunk = world.chunks[ChunkCoord{}]; len(chunk.CustomBiomes) != 256 || chunk.CustomBiomes[255] != "custom:lobby" {
+		t.Errorf("expected every column to be custom:lobby, got %d custom biomes", len(chunk.CustomBiomes))
+	}
+}
+
+func TestWriteAsSlime_customBiomes(t *testing.T) {
+	world := newTestWorld()
+	world.Flatten()
+	chunk := world.chunks[ChunkCoord{X: 1, Z: 1}]
+	chunk.Biomes[3] = plainsBiome
+	chunk.CustomBiomes = map[int]string{3: "mypack:crystal", 255: "minecraft:cherry_grove"}
+	world.chunks[ChunkCoord{X: 1, Z: 1}] = chunk
+
+	readBack := writeAndReadBack(t, world, SlimeWriteOptions{})
+	if err := world.VerifySlime(readBack, SlimeWriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	custom := readBack.chunks[ChunkCoord{X: 1, Z: 1}].CustomBiomes
+	if len(custom) != 2 || custom[3] != "mypack:crystal" || custom[255] != "minecraft:cherry_grove" {
+		t.Errorf("expected both custom biomes to be read back, got %v", custom)
+	}
+	if other := readBack.chunks[ChunkCoord{}].CustomBiomes; other != nil {
+		t.Errorf("expected chunks without custom biomes to be read back without them, got %v", other)
+	}
+
+	readBack.chunks[ChunkCoord{X: 1, Z: 1}].CustomBiomes[3] = "mypack:lobby"
+	if err := world.VerifySlime(readBack, SlimeWriteOptions{}); err == nil {
+		t.Error("expected a changed custom biome to fail verification")
+	}
+}
+
+func TestWriteAsSlime_biomeCells(t *testing.T) {
+	world := newTestWorld()
+	world.Flatten()
+	chunk := world.chunks[ChunkCoord{X: 1, Z: 1}]
+	ids := make([]int32, biomeCellsPerChunk)
+	for i := range ids {
+		ids[i] = int32(i >> 4 % 3)
+	}
+	chunk.setBiomesFromIDs(ids)
+	chunk.BiomeCells[biomeCellIndex(1, 2, 3)] = plainsBiome
+	chunk.CustomBiomeCells = map[int]string{biomeCellIndex(1, 2, 3): "mypack:crystal"}
+	world.chunks[ChunkCoord{X: 1, Z: 1}] = chunk
+
+	world.RemapBiomes(BiomeRemap{Mapping: map[string]string{"minecraft:desert": "minecraft:forest"}})
+	readBack := writeAndReadBack(t, world, SlimeWriteOptions{})
+	if err := world.VerifySlime(readBack, SlimeWriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	chunk = readBack.chunks[ChunkCoord{X: 1, Z: 1}]
+	if len(chunk.BiomeCells) != biomeCellsPerChunk || chunk.BiomeCells[biomeCellIndex(0, 2, 0)] != 4 ||
+		chunk.CustomBiomeCells[biomeCellIndex(1, 2, 3)] != "mypack:crystal" || len(chunk.CustomBiomeCells) != 1 {
+		t.Errorf("expected the remapped cells to be read back, got %d cells with custom biomes %v",
+			len(chunk.BiomeCells), chunk.CustomBiomeCells)
+	}
+	if cells := readBack.chunks[ChunkCoord{}].BiomeCells; cells != nil {
+		t.Errorf("expected chunks without cells to be read back without them, got %d cells", len(cells))
+	}
+
+	chunk.BiomeCells[0] = 5
+	if err := world.VerifySlime(readBack, SlimeWriteOptions{}); err == nil {
+		t.Error("expected a changed biome cell to fail verification")
+	}
+}
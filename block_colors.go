@@ -0,0 +1,178 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+)
+
+// blockColors are the colors that represent common blocks on a map, roughly the average color of their top face.
+// Blocks that are not listed fall back to a related block; see blockColor.
+var blockColors = map[string]color.RGBA{
+	"grass_block":       {95, 159, 53, 255},
+	"dirt":              {134, 96, 67, 255},
+	"coarse_dirt":       {119, 85, 59, 255},
+	"podzol":            {91, 63, 24, 255},
+	"mycelium":          {111, 99, 105, 255},
+	"farmland":          {81, 44, 15, 255},
+	"grass_path":        {148, 122, 65, 255},
+	"stone":             {125, 125, 125, 255},
+	"granite":           {149, 103, 85, 255},
+	"diorite":           {188, 188, 188, 255},
+	"andesite":          {136, 136, 136, 255},
+	"cobblestone":       {122, 122, 122, 255},
+	"mossy_cobblestone": {110, 118, 94, 255},
+	"bedrock":           {85, 85, 85, 255},
+	"sand":              {219, 207, 163, 255},
+	"red_sand":          {190, 102, 33, 255},
+	"gravel":            {131, 127, 126, 255},
+	"sandstone":         {216, 203, 155, 255},
+	"red_sandstone":     {186, 99, 29, 255},
+	"clay":              {160, 166, 179, 255},
+	"water":             {63, 118, 228, 255},
+	"lava":              {207, 92, 20, 255},
+	"ice":               {145, 183, 253, 255},
+	"packed_ice":        {141, 180, 250, 255},
+	"snow":              {249, 254, 254, 255},
+	"snow_block":        {249, 254, 254, 255},
+	"obsidian":          {15, 10, 24, 255},
+	"netherrack":        {97, 38, 38, 255},
+	"soul_sand":         {81, 62, 50, 255},
+	"glowstone":         {171, 131, 84, 255},
+	"end_stone":         {219, 222, 158, 255},
+	"bricks":            {150, 97, 83, 255},
+	"stone_bricks":      {122, 121, 122, 255},
+	"nether_bricks":     {44, 21, 26, 255},
+	"quartz_block":      {235, 229, 222, 255},
+	"prismarine":        {99, 171, 158, 255},
+	"purpur_block":      {169, 125, 169, 255},
+	"gold_block":        {246, 208, 61, 255},
+	"iron_block":        {220, 220, 220, 255},
+	"diamond_block":     {98, 237, 228, 255},
+	"emerald_block":     {42, 203, 87, 255},
+	"lapis_block":       {30, 67, 140, 255},
+	"redstone_block":    {175, 24, 5, 255},
+	"coal_block":        {16, 15, 15, 255},
+	"glass":             {175, 213, 219, 255},
+	"bookshelf":         {117, 94, 59, 255},
+	"chest":             {150, 108, 40, 255},
+	"trapped_chest":     {150, 108, 40, 255},
+	"ender_chest":       {44, 62, 64, 255},
+	"crafting_table":    {120, 80, 48, 255},
+	"furnace":           {110, 110, 110, 255},
+	"tnt":               {219, 68, 26, 255},
+	"pumpkin":           {198, 118, 24, 255},
+	"melon":             {111, 145, 30, 255},
+	"cactus":            {85, 127, 43, 255},
+	"hay_block":         {166, 136, 38, 255},
+	"sponge":            {195, 192, 74, 255},
+	"terracotta":        {152, 94, 67, 255},
+	"sugar_cane":        {148, 192, 101, 255},
+	"lily_pad":          {32, 128, 48, 255},
+}
+
+var dyeColors = map[string]color.RGBA{
+	"white":      {233, 236, 236, 255},
+	"orange":     {240, 118, 19, 255},
+	"magenta":    {189, 68, 179, 255},
+	"light_blue": {58, 175, 217, 255},
+	"yellow":     {248, 197, 39, 255},
+	"lime":       {112, 185, 25, 255},
+	"pink":       {237, 141, 172, 255},
+	"gray":       {62, 68, 71, 255},
+	"light_gray": {142, 142, 134, 255},
+	"cyan":       {21, 137, 145, 255},
+	"purple":     {121, 42, 172, 255},
+	"blue":       {53, 57, 157, 255},
+	"brown":      {114, 71, 40, 255},
+	"green":      {84, 109, 27, 255},
+	"red":        {160, 39, 34, 255},
+	"black":      {20, 21, 25, 255},
+}
+
+// dyedBlocks are the blocks that come in every dye color, named after the color.
+var dyedBlocks = map[string]bool{
+	"wool": true, "carpet": true, "concrete": true, "concrete_powder": true, "terracotta": true,
+	"glazed_terracotta": true, "stained_glass": true, "stained_glass_pane": true, "shulker_box": true, "bed": true,
+	"banner": true, "wall_banner": true,
+}
+
+// woodColors are the colors of the planks, logs and leaves of each kind of wood.
+var woodColors = map[string]struct{ planks, log, leaves color.RGBA }{
+	"oak":      {color.RGBA{162, 130, 78, 255}, color.RGBA{109, 85, 50, 255}, color.RGBA{60, 120, 30, 255}},
+	"spruce":   {color.RGBA{114, 84, 48, 255}, color.RGBA{58, 37, 16, 255}, color.RGBA{50, 90, 50, 255}},
+	"birch":    {color.RGBA{192, 175, 121, 255}, color.RGBA{216, 215, 210, 255}, color.RGBA{100, 140, 60, 255}},
+	"jungle":   {color.RGBA{160, 115, 80, 255}, color.RGBA{85, 67, 25, 255}, color.RGBA{50, 130, 20, 255}},
+	"acacia":   {color.RGBA{168, 90, 50, 255}, color.RGBA{103, 96, 86, 255}, color.RGBA{70, 120, 30, 255}},
+	"dark_oak": {color.RGBA{66, 43, 20, 255}, color.RGBA{60, 46, 26, 255}, color.RGBA{50, 100, 20, 255}},
+}
+
+// unknownBlockColor is used for blocks blockColor knows nothing about.
+var unknownBlockColor = color.RGBA{127, 127, 127, 255}
+
+// shapeSuffixes are the suffixes of blocks that are made of, and colored like, another block.
+var shapeSuffixes = []string{"_stairs", "_slab", "_wall", "_fence_gate", "_fence", "_pressure_plate", "_button"}
+
+// isAir reports whether a block is one of the kinds of air, which a map shows the block below of.
+func isAir(name string) bool {
+	return name == "minecraft:air" || name == "minecraft:cave_air" || name == "minecraft:void_air"
+}
+
+// blockColor returns the color that represents a block on a map, or a transparent color for air.
+func blockColor(name string) color.RGBA {
+	if isAir(name) {
+		return color.RGBA{}
+	}
+	if !strings.HasPrefix(name, "minecraft:") {
+		return unknownBlockColor
+	}
+	name = strings.TrimPrefix(name, "minecraft:")
+	if c, ok := blockColors[name]; ok {
+		return c
+	}
+
+	// Colored blocks such as wool, concrete and stained glass share their dye's color.
+	for dye, c := range dyeColors {
+		if strings.HasPrefix(name, dye+"_") && dyedBlocks[strings.TrimPrefix(name, dye+"_")] {
+			return c
+		}
+	}
+
+	name = strings.TrimPrefix(name, "stripped_")
+	for _, wood := range []string{"dark_oak", "oak", "spruce", "birch", "jungle", "acacia"} {
+		if !strings.HasPrefix(name, wood+"_") {
+			continue
+		}
+		colors := woodColors[wood]
+		switch {
+		case strings.HasSuffix(name, "_log") || strings.HasSuffix(name, "_wood"):
+			return colors.log
+		case strings.HasSuffix(name, "_leaves"):
+			return colors.leaves
+		default:
+			return colors.planks
+		}
+	}
+
+	for _, suffix := range shapeSuffixes {
+		if base := strings.TrimSuffix(name, suffix); base != name {
+			for _, candidate := range []string{base, base + "s", base + "_block"} {
+				if c, ok := blockColors[candidate]; ok {
+					return c
+				}
+			}
+		}
+	}
+	if strings.HasSuffix(name, "_ore") {
+		return blockColors["stone"]
+	}
+	return unknownBlockColor
+}
+
+// legacyBlockColors returns the color of every legacy block, indexed by id<<4 | data.
+func legacyBlockColors() *[256 * 16]color.RGBA {
+	var colors [256 * 16]color.RGBA
+	for i := range colors {
+		colors[i] = blockColor(flattenLegacyBlock(byte(i>>4), byte(i&15)).Name)
+	}
+	return &colors
+}
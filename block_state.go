@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BlockState is a block in the format used since 1.13: a namespaced name and a set of properties.
+type BlockState struct {
+	Name       string
+	Properties map[string]string
+}
+
+// ParseBlockState reads a block state written like minecraft:oak_stairs[facing=east,half=top]. The namespace defaults
+// to minecraft.
+func ParseBlockState(s string) (BlockState, error) {
+	name, properties := s, ""
+	if open := strings.IndexByte(s, '['); open >= 0 {
+		if !strings.HasSuffix(s, "]") {
+			return BlockState{}, fmt.Errorf("invalid block state %q: missing ]", s)
+		}
+		name, properties = s[:open], s[open+1:len(s)-1]
+	}
+	if name == "" {
+		return BlockState{}, fmt.Errorf("invalid block state %q: missing name", s)
+	}
+	if !strings.Contains(name, ":") {
+		name = "minecraft:" + name
+	}
+
+	state := BlockState{Name: name}
+	if properties != "" {
+		state.Properties = make(map[string]string)
+		for _, property := range strings.Split(properties, ",") {
+			parts := strings.SplitN(property, "=", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return BlockState{}, fmt.Errorf("invalid block state %q: bad property %q", s, property)
+			}
+			state.Properties[parts[0]] = parts[1]
+		}
+	}
+	return state, nil
+}
+
+// blockState is a shorthand for building block states in tables. Properties are given as pairs of names and values.
+func blockState(name string, properties ...string) BlockState {
+	state := BlockState{Name: "minecraft:" + name}
+	if len(properties) > 0 {
+		state.Properties = make(map[string]string, len(properties)/2)
+		for i := 0; i+1 < len(properties); i += 2 {
+			state.Properties[properties[i]] = properties[i+1]
+		}
+	}
+	return state
+}
+
+// String formats the state the way ParseBlockState reads it, with the properties sorted by name.
+func (s BlockState) String() string {
+	if len(s.Properties) == 0 {
+		return s.Name
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(s.Name)
+	b.WriteByte('[')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(s.Properties[name])
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// with returns a copy of the state with a property changed.
+func (s BlockState) with(name, value string) BlockState {
+	properties := make(map[string]string, len(s.Properties)+1)
+	for k, v := range s.Properties {
+		properties[k] = v
+	}
+	properties[name] = value
+	return BlockState{Name: s.Name, Properties: properties}
+}
+
+// toPaletteEntry converts the state to the compound stored in a section palette.
+func (s BlockState) toPaletteEntry() NBTCompound {
+	entry := NBTCompound{"Name": s.Name}
+	if len(s.Properties) > 0 {
+		properties := make(map[string]interface{}, len(s.Properties))
+		for name, value := range s.Properties {
+			properties[name] = value
+		}
+		entry["Properties"] = properties
+	}
+	return entry
+}
+
+func blockStateFromPaletteEntry(entry NBTCompound) BlockState {
+	name, _ := entry["Name"].(string)
+	state := BlockState{Name: name}
+	if properties, ok := entry.Compound("Properties"); ok && len(properties) > 0 {
+		state.Properties = make(map[string]string, len(properties))
+		for k, v := range properties {
+			if value, ok := v.(string); ok {
+				state.Properties[k] = value
+			}
+		}
+	}
+	return state
+}
+
+// paletteBuilder collects the distinct states of a section in the order they are first seen.
+type paletteBuilder struct {
+	states  []BlockState
+	indexes map[string]int
+}
+
+func (p *paletteBuilder) indexOf(state BlockState) int {
+	key := state.String()
+	if idx, ok := p.indexes[key]; ok {
+		return idx
+	}
+	if p.indexes == nil {
+		p.indexes = make(map[string]int)
+	}
+	p.indexes[key] = len(p.states)
+	p.states = append(p.states, state)
+	return len(p.states) - 1
+}
+
+func (p *paletteBuilder) entries() []NBTCompound {
+	entries := make([]NBTCompound, len(p.states))
+	for i, state := range p.states {
+		entries[i] = state.toPaletteEntry()
+	}
+	return entries
+}
+
+// paletteBitsPerBlock returns how many bits each block takes in the packed block states of a section, which is never
+// less than four.
+func paletteBitsPerBlock(paletteSize int) int {
+	bits := 4
+	for 1<<uint(bits) < paletteSize {
+		bits++
+	}
+	return bits
+}
+
+// packBlockStates packs palette indexes the way 1.13 through 1.15 do, with entries running over from one long into
+// the next.
+func packBlockStates(indexes []int, bits int) []int64 {
+	packed := make([]int64, (len(indexes)*bits+63)/64)
+	for i, idx := range indexes {
+		bit := i * bits
+		word, offset := bit/64, uint(bit%64)
+		packed[word] |= int64(uint64(idx) << offset)
+		if offset+uint(bits) > 64 {
+			packed[word+1] |= int64(uint64(idx) >> (64 - offset))
+		}
+	}
+	return packed
+}
+
+// unpackBlockStates reverses packBlockStates.
+func unpackBlockStates(packed []int64, bits, count int) []int {
+	indexes := make([]int, count)
+	mask := uint64(1)<<uint(bits) - 1
+	for i := range indexes {
+		bit := i * bits
+		word, offset := bit/64, uint(bit%64)
+		if word >= len(packed) {
+			break
+		}
+		value := uint64(packed[word]) >> offset
+		if offset+uint(bits) > 64 && word+1 < len(packed) {
+			value |= uint64(packed[word+1]) << (64 - offset)
+		}
+		indexes[i] = int(value & mask)
+	}
+	return indexes
+}
+
+// unpackPaddedIndexes unpacks palette indexes the way 1.16 and later pack them, starting a new long rather than
+// letting an index run over from one long into the next. Biomes are packed the same way from 1.18 on.
+func unpackPaddedIndexes(packed []int64, bits, count int) []int {
+	indexes := make([]int, count)
+	perLong := 64 / bits
+	mask := uint64(1)<<uint(bits) - 1
+	for i := range indexes {
+		word := i / perLong
+		if word >= len(packed) {
+			break
+		}
+		indexes[i] = int(uint64(packed[word]) >> uint(i%perLong*bits) & mask)
+	}
+	return indexes
+}
+
+// repackPaddedBlockStates packs block states that 1.16 and later packed the way packBlockStates does.
+func repackPaddedBlockStates(packed []int64, paletteSize int) []int64 {
+	bits := paletteBitsPerBlock(paletteSize)
+	return packBlockStates(unpackPaddedIndexes(packed, bits, 4096), bits)
+}
+
+// blockStates returns the state of every block in a section that uses a palette, in YZX order.
+func (section MinecraftChunkSection) blockStates() []BlockState {
+	states := make([]BlockState, 4096)
+	palette := make([]BlockState, len(section.Palette))
+	for i, entry := range section.Palette {
+		palette[i] = blockStateFromPaletteEntry(entry)
+	}
+	if len(palette) == 0 {
+		return states
+	}
+	bits := len(section.BlockStates) * 64 / 4096
+	for i, idx := range unpackBlockStates(section.BlockStates, bits, 4096) {
+		if idx < len(palette) {
+			states[i] = palette[idx]
+		} else {
+			states[i] = palette[0]
+		}
+	}
+	return states
+}
+
+// setBlockStates replaces the blocks of a section with the given states, in YZX order.
+func (section *MinecraftChunkSection) setBlockStates(states []BlockState) {
+	var palette paletteBuilder
+	indexes := make([]int, len(states))
+	for i, state := range states {
+		indexes[i] = palette.indexOf(state)
+	}
+	section.Palette = palette.entries()
+	section.BlockStates = packBlockStates(indexes, paletteBitsPerBlock(len(palette.states)))
+	section.Blocks = nil
+	section.Data = nil
+}
+
+// isEmpty reports whether a section holds nothing but air.
+func (section MinecraftChunkSection) isEmpty() bool {
+	if !section.usesPalette() {
+		return len(section.Blocks) == 0 || bytes.Equal(blank[:], section.Blocks)
+	}
+	for _, entry := range section.Palette {
+		if !isAir(blockStateFromPaletteEntry(entry).Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// usesPalette reports whether the section stores block states rather than legacy block ids.
+func (section MinecraftChunkSection) usesPalette() bool {
+	return section.Palette != nil
+}
@@ -1,27 +1,55 @@
 package main
 
-type MinecraftChunkRoot struct {
-	Level MinecraftChunk
-}
-
+// MinecraftChunk is a chunk as anvil2slime works with it, whichever format it was read from.
 type MinecraftChunk struct {
-	X int `nbt:"xPos"`
-	Z int `nbt:"zPos"`
+	X int
+	Z int
 
-	// We just need to store these - we do not care much about the actual content
-	Entities     []interface{}
-	TileEntities []interface{}
+	Entities     []NBTCompound
+	TileEntities []NBTCompound
+	// TileTicks and LiquidTicks are the block and fluid updates the game has scheduled in the chunk, such as repeaters
+	// about to switch and water about to flow. Each names the block or fluid in i and holds its absolute position in x,
+	// y and z.
+	TileTicks   []NBTCompound
+	LiquidTicks []NBTCompound
 
-	Biomes    []byte
+	// Biomes holds a numeric biome id for each column, in ZX order, as Slime files and worlds before 1.13 store them.
+	Biomes []byte
+	// CustomBiomes names the biomes that have no numeric id, such as biomes from data packs, by the index of their
+	// column in Biomes, which holds plains for them.
+	CustomBiomes map[int]string
+	// BiomeCells holds a numeric biome id for each 4x4x4 cell, in YZX order, for chunks from 1.15 on, which store
+	// biomes that way, and is nil for others. Each column in Biomes then takes the biome of its cell at sea level.
+	BiomeCells []byte
+	// CustomBiomeCells names the biomes of cells that have no numeric id, like CustomBiomes.
+	CustomBiomeCells map[int]string
+	// HeightMap holds the height of each column, in ZX order, the way worlds before 1.13 store it.
 	HeightMap []int
+	// HeightMaps holds the named height maps of 1.13 and later, such as MOTION_BLOCKING, each laid out like HeightMap.
+	HeightMaps map[string][]int
 
 	Sections []MinecraftChunkSection
+
+	// InhabitedTime is how many ticks players have spent near the chunk, or unknownInhabitedTime if the source did not
+	// keep it.
+	InhabitedTime int64
+	// Status is how far the game got with generating the chunk, as of 1.13. Finished chunks are "full".
+	Status string
+	// TerrainPopulated and LightPopulated are 1 once the game has decorated the chunk and calculated its light. Chunks
+	// from before 1.13 use them instead of Status.
+	TerrainPopulated byte
+	LightPopulated   byte
 }
 
+// MinecraftChunkSection is a 16x16x16 part of a chunk. BlockLight and SkyLight are nil if the source did not store
+// any light for the section. Sections store their blocks either as legacy block ids in Blocks and Data, or, once
+// flattened, as indexes into Palette packed into BlockStates.
 type MinecraftChunkSection struct {
-	Y          uint8
-	BlockLight []byte
-	Blocks     []byte
-	Data       []byte
-	SkyLight   []byte
+	Y           uint8
+	BlockLight  []byte
+	Blocks      []byte
+	Data        []byte
+	SkyLight    []byte
+	Palette     []NBTCompound
+	BlockStates []int64
 }
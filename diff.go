@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WorldDiff lists how one world differs from another. Chunks are compared by position; within a chunk, sections are
+// compared block by block, tile entities are matched by position and entities by UUID.
+type WorldDiff struct {
+	AddedChunks   []ChunkCoord `json:"addedChunks"`
+	RemovedChunks []ChunkCoord `json:"removedChunks"`
+	ChangedChunks []ChunkDiff  `json:"changedChunks"`
+}
+
+// ChunkDiff describes a chunk both worlds have that is not the same in each.
+type ChunkDiff struct {
+	X        int           `json:"x"`
+	Z        int           `json:"z"`
+	Sections []SectionDiff `json:"sections,omitempty"`
+	// BiomeColumns is how many columns have a different biome.
+	BiomeColumns int `json:"biomeColumns,omitempty"`
+	// BiomeCells is how many 4x4x4 cells have a different biome, for chunks from 1.15 on, which store one per cell.
+	BiomeCells   int            `json:"biomeCells,omitempty"`
+	TileEntities []CompoundDiff `json:"tileEntities,omitempty"`
+	Entities     []CompoundDiff `json:"entities,omitempty"`
+}
+
+// SectionDiff counts the blocks that differ in a section. A section only one world has is compared against air.
+type SectionDiff struct {
+	Y             int           `json:"y"`
+	ChangedBlocks int           `json:"changedBlocks"`
+	Changes       []BlockChange `json:"changes"`
+}
+
+// BlockChange is how many blocks of one kind became another kind. Changes are sorted from most to least common.
+type BlockChange struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// Kinds of change to a tile entity or entity.
+const (
+	CompoundAdded   = "added"
+	CompoundRemoved = "removed"
+	CompoundChanged = "changed"
+)
+
+// CompoundDiff describes a tile entity or entity that only one world has, or that is different in each.
+type CompoundDiff struct {
+	// Name identifies the tile entity or entity by its id and its position or UUID.
+	Name        string          `json:"name"`
+	Change      string          `json:"change"`
+	Differences []NBTDifference `json:"differences,omitempty"`
+}
+
+// NBTDifference is a tag that differs between two compounds. Path is written like Items[0].tag.display.Name, and
+// From or To is empty when the tag only exists on one side.
+type NBTDifference struct {
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// Empty reports whether the worlds are the same.
+func (d WorldDiff) Empty() bool {
+	return len(d.AddedChunks) == 0 && len(d.RemovedChunks) == 0 && len(d.ChangedChunks) == 0
+}
+
+func (d ChunkDiff) empty() bool {
+	return len(d.Sections) == 0 && d.BiomeColumns == 0 && d.BiomeCells == 0 && len(d.TileEntities) == 0 && len(d.Entities) == 0
+}
+
+// DiffWorlds compares two worlds, reporting what it would take to turn the first into the second. Light and height
+// maps are left out, since they follow from the blocks.
+func DiffWorlds(from, to *AnvilWorld) WorldDiff {
+	diff := WorldDiff{AddedChunks: []ChunkCoord{}, RemovedChunks: []ChunkCoord{}, ChangedChunks: []ChunkDiff{}}
+	for _, coord := range from.getSlimeSortedChunkKeys() {
+		toChunk, ok := to.chunks[coord]
+		if !ok {
+			diff.RemovedChunks = append(diff.RemovedChunks, coord)
+			continue
+		}
+		if chunkDiff := diffChunks(from.chunks[coord], toChunk); !chunkDiff.empty() {
+			chunkDiff.X, chunkDiff.Z = coord.X, coord.Z
+			diff.ChangedChunks = append(diff.ChangedChunks, chunkDiff)
+		}
+	}
+	for _, coord := range to.getSlimeSortedChunkKeys() {
+		if _, ok := from.chunks[coord]; !ok {
+			diff.AddedChunks = append(diff.AddedChunks, coord)
+		}
+	}
+	return diff
+}
+
+func diffChunks(from, to MinecraftChunk) ChunkDiff {
+	var diff ChunkDiff
+
+	fromSections := make(map[int]MinecraftChunkSection, len(from.Sections))
+	for _, section := range from.Sections {
+		fromSections[int(section.Y)] = section
+	}
+	toSections := make(map[int]MinecraftChunkSection, len(to.Sections))
+	for _, section := range to.Sections {
+		toSections[int(section.Y)] = section
+	}
+	var sectionYs []int
+	for y := range fromSections {
+		sectionYs = append(sectionYs, y)
+	}
+	for y := range toSections {
+		if _, ok := fromSections[y]; !ok {
+			sectionYs = append(sectionYs, y)
+		}
+	}
+	sort.Ints(sectionYs)
+	for _, y := range sectionYs {
+		fromSection, fromOK := fromSections[y]
+		toSection, toOK := toSections[y]
+		if sectionDiff := diffSections(fromSection, fromOK, toSection, toOK); sectionDiff.ChangedBlocks > 0 {
+			sectionDiff.Y = y
+			diff.Sections = append(diff.Sections, sectionDiff)
+		}
+	}
+
+	for i := 0; i < len(from.Biomes) || i < len(to.Biomes); i++ {
+		if i >= len(from.Biomes) || i >= len(to.Biomes) || from.Biomes[i] != to.Biomes[i] ||
+			from.CustomBiomes[i] != to.CustomBiomes[i] {
+			diff.BiomeColumns++
+		}
+	}
+	for i := 0; i < len(from.BiomeCells) || i < len(to.BiomeCells); i++ {
+		if i >= len(from.BiomeCells) || i >= len(to.BiomeCells) || from.BiomeCells[i] != to.BiomeCells[i] ||
+			from.CustomBiomeCells[i] != to.CustomBiomeCells[i] {
+			diff.BiomeCells++
+		}
+	}
+
+	diff.TileEntities = diffCompounds(from.TileEntities, to.TileEntities, tileEntityName)
+	diff.Entities = diffCompounds(from.Entities, to.Entities, entityName)
+	return diff
+}
+
+// sectionBlockNames returns the block state of every block in a section as a string, so that sections can be compared
+// whichever way they store their blocks. Missing sections are all air.
+func sectionBlockNames(section MinecraftChunkSection, exists bool) []string {
+	names := make([]string, 4096)
+	if !exists {
+		air := blockState("air").String()
+		for i := range names {
+			names[i] = air
+		}
+		return names
+	}
+	if section.usesPalette() {
+		for i, state := range section.blockStates() {
+			names[i] = state.String()
+		}
+		return names
+	}
+	legacyNames := make(map[int]string)
+	for i, id := range section.Blocks {
+		key := int(id)<<4 | int(getNibble(section.Data, i))
+		name, ok := legacyNames[key]
+		if !ok {
+			name = flattenLegacyBlock(id, byte(key&15)).String()
+			legacyNames[key] = name
+		}
+		names[i] = name
+	}
+	return names
+}
+
+func diffSections(from MinecraftChunkSection, fromOK bool, to MinecraftChunkSection, toOK bool) SectionDiff {
+	var diff SectionDiff
+	if fromOK && toOK && reflect.DeepEqual(from.Blocks, to.Blocks) && reflect.DeepEqual(from.Data, to.Data) &&
+		reflect.DeepEqual(from.Palette, to.Palette) && reflect.DeepEqual(from.BlockStates, to.BlockStates) {
+		return diff
+	}
+
+	fromNames, toNames := sectionBlockNames(from, fromOK), sectionBlockNames(to, toOK)
+	changes := make(map[[2]string]int)
+	for i := range fromNames {
+		if fromNames[i] != toNames[i] {
+			changes[[2]string{fromNames[i], toNames[i]}]++
+			diff.ChangedBlocks++
+		}
+	}
+	for change, count := range changes {
+		diff.Changes = append(diff.Changes, BlockChange{From: change[0], To: change[1], Count: count})
+	}
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		a, b := diff.Changes[i], diff.Changes[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return diff
+}
+
+func tileEntityName(tileEntity NBTCompound) string {
+	x, _ := tileEntity.Int("x")
+	y, _ := tileEntity.Int("y")
+	z, _ := tileEntity.Int("z")
+	return fmt.Sprintf("%s at %d,%d,%d", idOrUnknown(tileEntity), x, y, z)
+}
+
+// entityName names an entity by its UUID, which 1.16 stores as four ints and older versions as two longs. Entities
+// without one are named by their position instead.
+func entityName(entity NBTCompound) string {
+	if uuid, ok := entity["UUID"].([]int32); ok && len(uuid) == 4 {
+		most := uint64(uint32(uuid[0]))<<32 | uint64(uint32(uuid[1]))
+		least := uint64(uint32(uuid[2]))<<32 | uint64(uint32(uuid[3]))
+		return idOrUnknown(entity) + " " + formatUUID(most, least)
+	}
+	most, mostOK := entity["UUIDMost"].(int64)
+	least, leastOK := entity["UUIDLeast"].(int64)
+	if mostOK && leastOK {
+		return idOrUnknown(entity) + " " + formatUUID(uint64(most), uint64(least))
+	}
+	return fmt.Sprintf("%s at %s", idOrUnknown(entity), formatNBTValue(entity["Pos"]))
+}
+
+func formatUUID(most, least uint64) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", most>>32, most>>16&0xffff, most&0xffff, least>>48,
+		least&0xffffffffffff)
+}
+
+// diffCompounds matches compounds by name and compares the ones found in both lists. Compounds that share a name are
+// matched in order.
+func diffCompounds(from, to []NBTCompound, name func(NBTCompound) string) []CompoundDiff {
+	toByName := make(map[string][]NBTCompound)
+	for _, compound := range to {
+		toByName[name(compound)] = append(toByName[name(compound)], compound)
+	}
+
+	var diffs []CompoundDiff
+	for _, compound := range from {
+		compoundName := name(compound)
+		matches := toByName[compoundName]
+		if len(matches) == 0 {
+			diffs = append(diffs, CompoundDiff{Name: compoundName, Change: CompoundRemoved})
+			continue
+		}
+		toByName[compoundName] = matches[1:]
+		var differences []NBTDifference
+		diffNBT("", map[string]interface{}(compound), map[string]interface{}(matches[0]), &differences)
+		if len(differences) > 0 {
+			diffs = append(diffs, CompoundDiff{Name: compoundName, Change: CompoundChanged, Differences: differences})
+		}
+	}
+	for _, compound := range to {
+		compoundName := name(compound)
+		for _, added := range toByName[compoundName] {
+			diffs = append(diffs, CompoundDiff{Name: name(added), Change: CompoundAdded})
+		}
+		delete(toByName, compoundName)
+	}
+	return diffs
+}
+
+// diffNBT adds every tag that differs between two NBT values to differences, recursing into compounds and lists.
+func diffNBT(path string, from, to interface{}, differences *[]NBTDifference) {
+	fromCompound, fromIsCompound := asCompound(from)
+	toCompound, toIsCompound := asCompound(to)
+	if fromIsCompound && toIsCompound {
+		names := make(map[string]bool)
+		for name := range fromCompound {
+			names[name] = true
+		}
+		for name := range toCompound {
+			names[name] = true
+		}
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		for _, name := range sorted {
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			fromValue, fromOK := fromCompound[name]
+			toValue, toOK := toCompound[name]
+			switch {
+			case !toOK:
+				*differences = append(*differences, NBTDifference{Path: childPath, From: formatNBTValue(fromValue)})
+			case !fromOK:
+				*differences = append(*differences, NBTDifference{Path: childPath, To: formatNBTValue(toValue)})
+			default:
+				diffNBT(childPath, fromValue, toValue, differences)
+			}
+		}
+		return
+	}
+
+	fromList, fromIsList := nbtList(from)
+	toList, toIsList := nbtList(to)
+	if fromIsList && toIsList {
+		for i := 0; i < len(fromList) || i < len(toList); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(toList):
+				*differences = append(*differences, NBTDifference{Path: childPath, From: formatNBTValue(fromList[i])})
+			case i >= len(fromList):
+				*differences = append(*differences, NBTDifference{Path: childPath, To: formatNBTValue(toList[i])})
+			default:
+				diffNBT(childPath, fromList[i], toList[i], differences)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*differences = append(*differences, NBTDifference{Path: path, From: formatNBTValue(from),
+			To: formatNBTValue(to)})
+	}
+}
+
+// nbtList returns the entries of an NBT list. Arrays of numbers are not lists and are compared as a whole.
+func nbtList(value interface{}) ([]interface{}, bool) {
+	switch list := value.(type) {
+	case []interface{}:
+		return list, true
+	case []NBTCompound:
+		entries := make([]interface{}, len(list))
+		for i, compound := range list {
+			entries[i] = compound
+		}
+		return entries, true
+	default:
+		return nil, false
+	}
+}
+
+// maxNBTValueLength is how long formatNBTValue lets a value get before cutting it short.
+const maxNBTValueLength = 80
+
+// formatNBTValue writes an NBT value for a person to read, marking the type of numbers the way commands in the game
+// do.
+func formatNBTValue(value interface{}) string {
+	var text string
+	switch v := value.(type) {
+	case byte:
+		text = fmt.Sprintf("%db", int8(v))
+	case int16:
+		text = fmt.Sprintf("%ds", v)
+	case int32:
+		text = fmt.Sprintf("%d", v)
+	case int64:
+		text = fmt.Sprintf("%dL", v)
+	case float32:
+		text = fmt.Sprintf("%gf", v)
+	case float64:
+		text = fmt.Sprintf("%gd", v)
+	case string:
+		text = fmt.Sprintf("%q", v)
+	default:
+		text = fmt.Sprintf("%v", v)
+	}
+	if len(text) > maxNBTValueLength {
+		text = text[:maxNBTValueLength-3] + "..."
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Exit statuses of the diff command, which follow diff(1).
+const (
+	exitWorldsDiffer = 1
+	exitDiffTrouble  = 2
+)
+
+var diffCommand = &cli.Command{
+	Name:      "diff",
+	Usage:     "compares two worlds and reports how they differ",
+	ArgsUsage: "FROM TO",
+	Description: "FROM and TO are Anvil world directories or Slime files. The report lists chunks that were added or " +
+		"removed, and for chunks in both worlds, the blocks that changed in each section and the tile entities and " +
+		"entities that were added, removed or changed, down to the NBT tags that differ. The command exits with " +
+		"status 0 if the worlds are the same, 1 if they differ and 2 if they could not be compared.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "writes the report as `FORMAT` (text or json)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return exitStatusError{status: exitDiffTrouble, err: errors.New("need exactly two worlds to compare")}
+		}
+		var printDiff func(io.Writer, WorldDiff) error
+		switch format := c.String("format"); format {
+		case "text":
+			printDiff = printWorldDiffText
+		case "json":
+			printDiff = printWorldDiffJSON
+		default:
+			return exitStatusError{status: exitDiffTrouble,
+				err: fmt.Errorf("unknown report format %q (expected text or json)", format)}
+		}
+
+		diff, err := diffWorldFiles(c.Context, c.Args().Get(0), c.Args().Get(1), anvilReadOptionsFromFlags(c))
+		if err != nil {
+			return exitStatusError{status: exitDiffTrouble, err: err}
+		}
+		if err = printDiff(os.Stdout, diff); err != nil {
+			return exitStatusError{status: exitDiffTrouble, err: err}
+		}
+		if !diff.Empty() {
+			return exitStatusError{status: exitWorldsDiffer}
+		}
+		return nil
+	},
+}
+
+func diffWorldFiles(ctx context.Context, fromPath, toPath string, options AnvilReadOptions) (WorldDiff, error) {
+	from, err := OpenWorld(ctx, fromPath, options, newProgressReporter())
+	if err != nil {
+		return WorldDiff{}, fmt.Errorf("%s: %w", fromPath, err)
+	}
+	to, err := OpenWorld(ctx, toPath, options, newProgressReporter())
+	if err != nil {
+		return WorldDiff{}, fmt.Errorf("%s: %w", toPath, err)
+	}
+	return DiffWorlds(from, to), nil
+}
+
+// printWorldDiffText writes one line per difference, marking additions with +, removals with - and changes with ~.
+func printWorldDiffText(out io.Writer, diff WorldDiff) error {
+	if diff.Empty() {
+		_, err := fmt.Fprintln(out, "worlds are the same")
+		return err
+	}
+
+	w := &errWriter{out: out}
+	for _, coord := range diff.RemovedChunks {
+		w.printf("- chunk %d,%d\n", coord.X, coord.Z)
+	}
+	for _, coord := range diff.AddedChunks {
+		w.printf("+ chunk %d,%d\n", coord.X, coord.Z)
+	}
+	for _, chunk := range diff.ChangedChunks {
+		w.printf("~ chunk %d,%d\n", chunk.X, chunk.Z)
+		for _, section := range chunk.Sections {
+			w.printf("    ~ section %d: %d blocks changed\n", section.Y, section.ChangedBlocks)
+			for _, change := range section.Changes {
+				w.printf("        %d %s -> %s\n", change.Count, change.From, change.To)
+			}
+		}
+		if chunk.BiomeColumns > 0 {
+			w.printf("    ~ biomes: %d columns changed\n", chunk.BiomeColumns)
+		}
+		if chunk.BiomeCells > 0 {
+			w.printf("    ~ biome cells: %d cells changed\n", chunk.BiomeCells)
+		}
+		printCompoundDiffs(w, "tile entity", chunk.TileEntities)
+		printCompoundDiffs(w, "entity", chunk.Entities)
+	}
+	w.printf("%d chunks removed, %d added, %d changed\n", len(diff.RemovedChunks), len(diff.AddedChunks),
+		len(diff.ChangedChunks))
+	return w.err
+}
+
+func printCompoundDiffs(w *errWriter, kind string, diffs []CompoundDiff) {
+	for _, diff := range diffs {
+		switch diff.Change {
+		case CompoundAdded:
+			w.printf("    + %s %s\n", kind, diff.Name)
+		case CompoundRemoved:
+			w.printf("    - %s %s\n", kind, diff.Name)
+		default:
+			w.printf("    ~ %s %s\n", kind, diff.Name)
+		}
+		for _, difference := range diff.Differences {
+			switch {
+			case difference.To == "":
+				w.printf("        - %s: %s\n", difference.Path, difference.From)
+			case difference.From == "":
+				w.printf("        + %s: %s\n", difference.Path, difference.To)
+			default:
+				w.printf("        ~ %s: %s -> %s\n", difference.Path, difference.From, difference.To)
+			}
+		}
+	}
+}
+
+func printWorldDiffJSON(out io.Writer, diff WorldDiff) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diff)
+}
+
+// errWriter remembers the first error writing to out, so a report can be written without checking every line.
+type errWriter struct {
+	out io.Writer
+	err error
+}
+
+func (w *errWriter) printf(format string, args ...interface{}) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.out, format, args...)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffWorlds(t *testing.T) {
+	from, to := newTestWorld(), newTestWorld()
+	if diff := DiffWorlds(from, to); !diff.Empty() {
+		t.Fatalf("expected identical worlds, got %+v", diff)
+	}
+
+	delete(to.chunks, ChunkCoord{X: -3, Z: -2})
+	to.chunks[ChunkCoord{X: 5, Z: 5}] = newTestChunk(5, 5)
+	chunk := to.chunks[ChunkCoord{X: 0, Z: 0}]
+	chunk.Sections[0].Blocks[0] = 0
+	chunk.Sections[0].Blocks[1] = 0
+	chunk.Sections[0].Blocks[2] = 35
+	chunk.Biomes = make([]byte, 256)
+	chunk.Biomes[7] = 1
+	chunk.TileEntities[0] = NBTCompound{"id": "minecraft:sign", "x": int32(0), "y": int32(1), "z": int32(0),
+		"Text1": "goodbye"}
+	chunk.Entities = append(chunk.Entities, NBTCompound{"id": "minecraft:cow", "UUIDMost": int64(1),
+		"UUIDLeast": int64(2)})
+	to.chunks[ChunkCoord{X: 0, Z: 0}] = chunk
+
+	diff := DiffWorlds(from, to)
+	if len(diff.RemovedChunks) != 1 || diff.RemovedChunks[0] != (ChunkCoord{X: -3, Z: -2}) {
+		t.Errorf("unexpected removed chunks %v", diff.RemovedChunks)
+	}
+	if len(diff.AddedChunks) != 1 || diff.AddedChunks[0] != (ChunkCoord{X: 5, Z: 5}) {
+		t.Errorf("unexpected added chunks %v", diff.AddedChunks)
+	}
+	if len(diff.ChangedChunks) != 1 {
+		t.Fatalf("expected one changed chunk, got %+v", diff.ChangedChunks)
+	}
+
+	changed := diff.ChangedChunks[0]
+	if len(changed.Sections) != 1 || changed.Sections[0].ChangedBlocks != 3 {
+		t.Fatalf("expected 3 changed blocks, got %+v", changed.Sections)
+	}
+	expectedChanges := []BlockChange{
+		{From: "minecraft:stone", To: "minecraft:air", Count: 2},
+		{From: "minecraft:stone", To: "minecraft:white_wool", Count: 1},
+	}
+	for i, change := range changed.Sections[0].Changes {
+		if change != expectedChanges[i] {
+			t.Errorf("expected change %v, got %v", expectedChanges[i], change)
+		}
+	}
+	if changed.BiomeColumns != 1 {
+		t.Errorf("expected 1 changed biome column, got %d", changed.BiomeColumns)
+	}
+	if len(changed.TileEntities) != 1 || changed.TileEntities[0].Name != "minecraft:sign at 0,1,0" {
+		t.Fatalf("unexpected tile entity changes %+v", changed.TileEntities)
+	}
+	expectedDifferences := []NBTDifference{
+		{Path: "CustomName", From: `"sign"`},
+		{Path: "Text1", From: `"hello"`, To: `"goodbye"`},
+	}
+	for i, difference := range changed.TileEntities[0].Differences {
+		if difference != expectedDifferences[i] {
+			t.Errorf("expected difference %v, got %v", expectedDifferences[i], difference)
+		}
+	}
+	if len(changed.Entities) != 1 || changed.Entities[0].Change != CompoundAdded ||
+		changed.Entities[0].Name != "minecraft:cow 00000000-0000-0001-0000-000000000002" {
+		t.Errorf("unexpected entity changes %+v", changed.Entities)
+	}
+
+	var out bytes.Buffer
+	if err := printWorldDiffText(&out, diff); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "        ~ Text1: \"hello\" -> \"goodbye\"\n") ||
+		!strings.HasSuffix(out.String(), "1 chunks removed, 1 added, 1 changed\n") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestDiffNBT_lists(t *testing.T) {
+	from := map[string]interface{}{
+		"Items": []interface{}{
+			map[string]interface{}{"id": "minecraft:stone", "Count": byte(1)},
+		},
+	}
+	to := map[string]interface{}{
+		"Items": []interface{}{
+			map[string]interface{}{"id": "minecraft:stone", "Count": byte(2)},
+			map[string]interface{}{"id": "minecraft:dirt", "Count": byte(1)},
+		},
+	}
+	var differences []NBTDifference
+	diffNBT("", from, to, &differences)
+	if len(differences) != 2 || differences[0] != (NBTDifference{Path: "Items[0].Count", From: "1b", To: "2b"}) ||
+		differences[1].Path != "Items[1]" || differences[1].From != "" {
+		t.Errorf("unexpected differences %+v", differences)
+	}
+}
+
+func TestDiffWorlds_biomeCells(t *testing.T) {
+	newWorld := func() *AnvilWorld {
+		chunk := newTestChunk(0, 0)
+		chunk.setBiomesFromIDs(make([]int32, biomeCellsPerChunk))
+		return &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{}: chunk}}
+	}
+	from, to := newWorld(), newWorld()
+	chunk := to.chunks[ChunkCoord{}]
+	chunk.BiomeCells[biomeCellIndex(1, 2, 3)] = 1
+	chunk.CustomBiomeCells = map[int]string{biomeCellIndex(0, 0, 0): "mypack:crystal"}
+	to.chunks[ChunkCoord{}] = chunk
+
+	diff := DiffWorlds(from, to)
+	if len(diff.ChangedChunks) != 1 || diff.ChangedChunks[0].BiomeColumns != 0 ||
+		diff.ChangedChunks[0].BiomeCells != 2 {
+		t.Fatalf("expected 2 changed biome cells and no changed columns, got %+v", diff.ChangedChunks)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// IDFilter decides which entities or tile entities to keep by their id. Patterns use glob syntax, so
+// "*:command_block" matches command blocks from any namespace. Ids are matched exactly as stored, so worlds from
+// before 1.11 use names like "Item" rather than "minecraft:item".
+type IDFilter struct {
+	// Include, if not empty, keeps only ids that match at least one of its patterns.
+	Include []string
+	// Exclude removes ids that match any of its patterns, even if they are included.
+	Exclude []string
+}
+
+// Validate checks that every pattern is well formed.
+func (f IDFilter) Validate() error {
+	for _, pattern := range append(append([]string(nil), f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid id pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (f IDFilter) empty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Keeps reports whether the filter keeps the given id.
+func (f IDFilter) Keeps(id string) bool {
+	if len(f.Include) > 0 && !matchesAny(f.Include, id) {
+		return false
+	}
+	return !matchesAny(f.Exclude, id)
+}
+
+func matchesAny(patterns []string, id string) bool {
+	for _, pattern := range patterns {
+		// Patterns are validated up front, so errors cannot happen here.
+		if matched, _ := path.Match(pattern, id); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// RemovedIDs counts what a filter removed by id, or for chunks, by the reason they were dropped.
+type RemovedIDs map[string]int
+
+// Total returns how many were removed in all.
+func (r RemovedIDs) Total() int {
+	total := 0
+	for _, count := range r {
+		total += count
+	}
+	return total
+}
+
+// IDs returns the removed ids in order.
+func (r RemovedIDs) IDs() []string {
+	ids := make([]string, 0, len(r))
+	for id := range r {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// FilterEntities removes the entities and tile entities that the filters do not keep and returns what was removed.
+// Passengers are not checked on their own: they stay or go with the entity they ride.
+func (world *AnvilWorld) FilterEntities(entities, tileEntities IDFilter) (removedEntities, removedTileEntities RemovedIDs) {
+	removedEntities = make(RemovedIDs)
+	removedTileEntities = make(RemovedIDs)
+	for coord, chunk := range world.chunks {
+		if !entities.empty() {
+			chunk.Entities = filterCompounds(chunk.Entities, entities, removedEntities)
+		}
+		if !tileEntities.empty() {
+			chunk.TileEntities = filterCompounds(chunk.TileEntities, tileEntities, removedTileEntities)
+		}
+		world.chunks[coord] = chunk
+	}
+	return
+}
+
+func filterCompounds(compounds []NBTCompound, filter IDFilter, removed RemovedIDs) []NBTCompound {
+	kept := compounds[:0]
+	for _, compound := range compounds {
+		if filter.Keeps(compound.ID()) {
+			kept = append(kept, compound)
+		} else {
+			removed[compound.ID()]++
+		}
+	}
+	return kept
+}
+
+// unknownInhabitedTime marks chunks whose source did not keep their InhabitedTime. ChunkFilter never drops them for it.
+const unknownInhabitedTime = -1
+
+// chunkStatusFull is the Status of chunks the game has finished generating.
+const chunkStatusFull = "full"
+
+// Reasons ChunkFilter drops a chunk for.
+const (
+	DroppedUninhabited = "inhabited time below threshold"
+	DroppedUnfinished  = "status not full"
+	DroppedUnpopulated = "terrain not populated"
+	DroppedUnlit       = "light not populated"
+)
+
+// ChunkFilter decides which chunks to keep by how much players visited them and how far the game got with generating
+// them, to leave out terrain that was generated ahead of time but never played in.
+type ChunkFilter struct {
+	// MinInhabitedTime, if positive, drops chunks that players spent fewer ticks near.
+	MinInhabitedTime int64
+	// RequireFull drops chunks whose Status is not full, or for chunks from before 1.13, whose terrain or light was
+	// never populated.
+	RequireFull bool
+}
+
+func (f ChunkFilter) empty() bool {
+	return f.MinInhabitedTime <= 0 && !f.RequireFull
+}
+
+// dropReason returns why the filter drops a chunk, or an empty string if it keeps it.
+func (f ChunkFilter) dropReason(chunk MinecraftChunk) string {
+	if f.MinInhabitedTime > 0 && chunk.InhabitedTime != unknownInhabitedTime && chunk.InhabitedTime < f.MinInhabitedTime {
+		return DroppedUninhabited
+	}
+	if !f.RequireFull {
+		return ""
+	}
+	switch {
+	case chunk.Status != "":
+		if !chunkStatusFinished(chunk.Status) {
+			return DroppedUnfinished
+		}
+	case chunk.TerrainPopulated == 0:
+		return DroppedUnpopulated
+	case chunk.LightPopulated == 0:
+		return DroppedUnlit
+	}
+	return ""
+}
+
+// chunkStatusFinished reports whether a Status means the game has finished generating a chunk. 1.13 calls finished
+// chunks postprocessed, and 1.20 namespaces the status.
+func chunkStatusFinished(status string) bool {
+	status = strings.TrimPrefix(status, "minecraft:")
+	return status == chunkStatusFull || status == "postprocessed"
+}
+
+// FilterChunks removes the chunks that the filter does not keep and returns how many it removed for each reason.
+func (world *AnvilWorld) FilterChunks(filter ChunkFilter) (dropped RemovedIDs) {
+	dropped = make(RemovedIDs)
+	for coord, chunk := range world.chunks {
+		if reason := filter.dropReason(chunk); reason != "" {
+			delete(world.chunks, coord)
+			dropped[reason]++
+		}
+	}
+	return
+}
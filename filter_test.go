@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+func TestIDFilter_Keeps(t *testing.T) {
+	filter := IDFilter{Include: []string{"minecraft:*"}, Exclude: []string{"minecraft:item", "*:command_block"}}
+	for id, expected := range map[string]bool{
+		"minecraft:pig":           true,
+		"minecraft:item":          false,
+		"minecraft:command_block": false,
+		"custom:statue":           false,
+	} {
+		if kept := filter.Keeps(id); kept != expected {
+			t.Errorf("%s: expected kept=%v, got %v", id, expected, kept)
+		}
+	}
+	if err := (IDFilter{Exclude: []string{"["}}).Validate(); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestFilterEntities(t *testing.T) {
+	world := newTestWorld()
+	chunk := world.chunks[ChunkCoord{X: 0, Z: 0}]
+	chunk.Entities = append(chunk.Entities, NBTCompound{"id": "minecraft:item"}, NBTCompound{"id": "minecraft:item"})
+	chunk.TileEntities = append(chunk.TileEntities, NBTCompound{"id": "minecraft:command_block"})
+	world.chunks[ChunkCoord{X: 0, Z: 0}] = chunk
+	entityCount := countEntities(world)
+
+	removedEntities, removedTileEntities := world.FilterEntities(
+		IDFilter{Exclude: []string{"minecraft:item"}},
+		IDFilter{Exclude: []string{"*:command_block"}},
+	)
+	if removedEntities["minecraft:item"] != 2 || removedEntities.Total() != 2 {
+		t.Errorf("unexpected removed entities %v", removedEntities)
+	}
+	if removedTileEntities["minecraft:command_block"] != 1 || removedTileEntities.Total() != 1 {
+		t.Errorf("unexpected removed tile entities %v", removedTileEntities)
+	}
+	if remaining := countEntities(world); remaining != entityCount-2 {
+		t.Errorf("expected %d entities to remain, got %d", entityCount-2, remaining)
+	}
+}
+
+func countEntities(world *AnvilWorld) int {
+	count := 0
+	for _, chunk := range world.chunks {
+		count += len(chunk.Entities)
+	}
+	return count
+}
+
+func TestFilterChunks(t *testing.T) {
+	world := &AnvilWorld{chunks: make(map[ChunkCoord]MinecraftChunk)}
+	for i, chunk := range []MinecraftChunk{
+		{InhabitedTime: 1200, Status: "full"},
+		{InhabitedTime: 1200, Status: "minecraft:full"},
+		{InhabitedTime: 1200, Status: "postprocessed"},
+		{InhabitedTime: 1200, TerrainPopulated: 1, LightPopulated: 1},
+		{InhabitedTime: unknownInhabitedTime, Status: "full"},
+		{InhabitedTime: 5, Status: "full"},
+		{InhabitedTime: 0, Status: "features"},
+		{InhabitedTime: 1200, Status: "liquid_carvers"},
+		{InhabitedTime: 1200, TerrainPopulated: 0, LightPopulated: 1},
+		{InhabitedTime: 1200, TerrainPopulated: 1, LightPopulated: 0},
+	} {
+		chunk.X = i
+		world.chunks[ChunkCoord{X: i}] = chunk
+	}
+
+	dropped := world.FilterChunks(ChunkFilter{MinInhabitedTime: 20, RequireFull: true})
+	for reason, count := range map[string]int{
+		DroppedUninhabited: 2,
+		DroppedUnfinished:  1,
+		DroppedUnpopulated: 1,
+		DroppedUnlit:       1,
+	} {
+		if dropped[reason] != count {
+			t.Errorf("expected %d chunks dropped for %s, got %d", count, reason, dropped[reason])
+		}
+	}
+	if len(world.chunks) != 5 {
+		t.Errorf("expected 5 chunks to remain, got %d", len(world.chunks))
+	}
+	for x := 0; x < 5; x++ {
+		if _, ok := world.chunks[ChunkCoord{X: x}]; !ok {
+			t.Errorf("expected chunk %d to be kept", x)
+		}
+	}
+}
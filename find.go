@@ -0,0 +1,499 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of things a query can find.
+const (
+	FindBlock      = "block"
+	FindTileEntity = "tile entity"
+	FindEntity     = "entity"
+)
+
+// Query finds blocks, tile entities or entities. Queries are written as
+//
+//	block PATTERN
+//	tile [ID] [where CONDITION [and CONDITION ...]]
+//	entity [ID] [where CONDITION [and CONDITION ...]]
+//
+// where PATTERN is a block as --replace-blocks writes them, ID is a glob pattern and each CONDITION is an NBT path,
+// optionally followed by an operator (==, !=, <, <=, >, >= or contains) and a quoted string or a number. A path
+// without an operator checks that the tag exists. Paths name compound tags separated by dots, and pick list entries
+// with [N] or any entry with [], as in Items[].id == "minecraft:diamond". Conditions whose paths share everything up
+// to a [] apply to the same entry, so Items[].id == "minecraft:diamond" and Items[].Count > 10 finds a stack of more
+// than 10 diamonds rather than diamonds and some other stack of more than 10.
+type Query struct {
+	// Text is the query as it was written, for reporting.
+	Text       string
+	kind       string
+	block      BlockPattern
+	id         string
+	conditions []nbtCondition
+}
+
+type nbtCondition struct {
+	path     []pathElement
+	operator string
+	// value is a string or a float64, or nil for conditions that only check that the path exists.
+	value interface{}
+}
+
+// pathElement is a compound tag name, or a list index when list is set. An index of -1 stands for any entry.
+type pathElement struct {
+	name  string
+	list  bool
+	index int
+}
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ParseQuery parses a query written in the syntax described by Query.
+func ParseQuery(text string) (Query, error) {
+	query := Query{Text: text}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return query, fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), fields[0]))
+	switch fields[0] {
+	case "block":
+		query.kind = FindBlock
+		if len(fields) != 2 {
+			return query, fmt.Errorf("%w %q: expected block PATTERN", ErrInvalidQuery, text)
+		}
+		var err error
+		if query.block, err = ParseBlockPattern(fields[1]); err != nil {
+			return query, fmt.Errorf("%w %q: %v", ErrInvalidQuery, text, err)
+		}
+		return query, nil
+	case "tile":
+		query.kind = FindTileEntity
+	case "entity":
+		query.kind = FindEntity
+	default:
+		return query, fmt.Errorf("%w %q: unknown kind %q (expected block, tile or entity)", ErrInvalidQuery, text,
+			fields[0])
+	}
+
+	query.id = "*"
+	if len(fields) > 1 && fields[1] != "where" {
+		query.id = fields[1]
+		if _, err := path.Match(query.id, ""); err != nil {
+			return query, fmt.Errorf("%w %q: invalid id pattern %q", ErrInvalidQuery, text, query.id)
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, fields[1]))
+	}
+	if rest == "" {
+		return query, nil
+	}
+	if !strings.HasPrefix(rest, "where ") {
+		return query, fmt.Errorf("%w %q: expected where after the id", ErrInvalidQuery, text)
+	}
+
+	tokens, err := tokenizeConditions(strings.TrimPrefix(rest, "where "))
+	if err != nil {
+		return query, fmt.Errorf("%w %q: %v", ErrInvalidQuery, text, err)
+	}
+	if query.conditions, err = parseConditions(tokens); err != nil {
+		return query, fmt.Errorf("%w %q: %v", ErrInvalidQuery, text, err)
+	}
+	return query, nil
+}
+
+var queryOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// tokenizeConditions splits conditions into paths, operators, quoted strings and words. Quoted strings keep their
+// quotes so that the parser can tell them from words.
+func tokenizeConditions(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(text) && text[end] != '"'; end++ {
+				if text[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(text) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, text[i:end+1])
+			i = end + 1
+		default:
+			operator := ""
+			for _, candidate := range queryOperators {
+				if strings.HasPrefix(text[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator != "" {
+				tokens = append(tokens, operator)
+				i += len(operator)
+				continue
+			}
+			end := i
+			for end < len(text) && !strings.ContainsRune(" \t\"=!<>", rune(text[end])) {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("unexpected %q", text[i:])
+			}
+			tokens = append(tokens, text[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func parseConditions(tokens []string) ([]nbtCondition, error) {
+	var conditions []nbtCondition
+	for len(tokens) > 0 {
+		if len(conditions) > 0 {
+			if tokens[0] != "and" {
+				return nil, fmt.Errorf("expected and before %q", tokens[0])
+			}
+			tokens = tokens[1:]
+			if len(tokens) == 0 {
+				return nil, errors.New("expected a condition after and")
+			}
+		}
+
+		var condition nbtCondition
+		var err error
+		if condition.path, err = parseNBTPath(tokens[0]); err != nil {
+			return nil, err
+		}
+		tokens = tokens[1:]
+		if len(tokens) > 0 && tokens[0] != "and" {
+			condition.operator = tokens[0]
+			if !isQueryOperator(condition.operator) {
+				return nil, fmt.Errorf("unknown operator %q", condition.operator)
+			}
+			if len(tokens) < 2 {
+				return nil, fmt.Errorf("expected a value after %s", condition.operator)
+			}
+			if condition.value, err = parseQueryValue(tokens[1]); err != nil {
+				return nil, err
+			}
+			if _, isString := condition.value.(string); !isString && condition.operator == "contains" {
+				return nil, errors.New("contains needs a string")
+			}
+			tokens = tokens[2:]
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+func isQueryOperator(operator string) bool {
+	if operator == "contains" {
+		return true
+	}
+	for _, candidate := range queryOperators {
+		if operator == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func parseQueryValue(token string) (interface{}, error) {
+	if strings.HasPrefix(token, `"`) {
+		return strconv.Unquote(token)
+	}
+	number, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q (expected a quoted string or a number)", token)
+	}
+	return number, nil
+}
+
+// parseNBTPath parses a path such as Items[].tag.display.Name.
+func parseNBTPath(text string) ([]pathElement, error) {
+	var elements []pathElement
+	for _, part := range strings.Split(text, ".") {
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+		}
+		if name == "" && (len(elements) == 0 || name == part) {
+			return nil, fmt.Errorf("invalid path %q", text)
+		}
+		if name != "" {
+			elements = append(elements, pathElement{name: name})
+		}
+		for rest := part[len(name):]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid path %q", text)
+			}
+			element := pathElement{list: true, index: -1}
+			if index := rest[1:end]; index != "" {
+				var err error
+				if element.index, err = strconv.Atoi(index); err != nil || element.index < 0 {
+					return nil, fmt.Errorf("invalid list index %q in path %q", index, text)
+				}
+			}
+			elements = append(elements, element)
+			rest = rest[end+1:]
+		}
+	}
+	return elements, nil
+}
+
+// matchConditions reports whether value satisfies every condition, each given by the part of its path still to
+// follow. Conditions that follow the same path to a [] must be satisfied by the same list entry.
+func matchConditions(value interface{}, conditions []nbtCondition) bool {
+	var elements []pathElement
+	byElement := make(map[pathElement][]nbtCondition)
+	for _, condition := range conditions {
+		if len(condition.path) == 0 {
+			if condition.operator != "" && !condition.compare(value) {
+				return false
+			}
+			continue
+		}
+		element := condition.path[0]
+		if _, ok := byElement[element]; !ok {
+			elements = append(elements, element)
+		}
+		condition.path = condition.path[1:]
+		byElement[element] = append(byElement[element], condition)
+	}
+	for _, element := range elements {
+		if !matchElement(value, element, byElement[element]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchElement reports whether the tag or list entry element picks from value satisfies the conditions, or for [],
+// whether any one entry satisfies all of them.
+func matchElement(value interface{}, element pathElement, conditions []nbtCondition) bool {
+	if !element.list {
+		compound, ok := asCompound(value)
+		if !ok {
+			return false
+		}
+		child, ok := compound[element.name]
+		return ok && matchConditions(child, conditions)
+	}
+
+	list, ok := nbtList(value)
+	if !ok {
+		return false
+	}
+	if element.index >= 0 {
+		return element.index < len(list) && matchConditions(list[element.index], conditions)
+	}
+	for _, entry := range list {
+		if matchConditions(entry, conditions) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c nbtCondition) compare(value interface{}) bool {
+	switch expected := c.value.(type) {
+	case string:
+		actual, ok := value.(string)
+		if !ok {
+			return false
+		}
+		switch c.operator {
+		case "contains":
+			return strings.Contains(actual, expected)
+		case "==":
+			return actual == expected
+		case "!=":
+			return actual != expected
+		case "<":
+			return actual < expected
+		case "<=":
+			return actual <= expected
+		case ">":
+			return actual > expected
+		default:
+			return actual >= expected
+		}
+	case float64:
+		actual, ok := nbtNumber(value)
+		if !ok {
+			return false
+		}
+		switch c.operator {
+		case "==":
+			return actual == expected
+		case "!=":
+			return actual != expected
+		case "<":
+			return actual < expected
+		case "<=":
+			return actual <= expected
+		case ">":
+			return actual > expected
+		default:
+			return actual >= expected
+		}
+	}
+	return false
+}
+
+// nbtNumber returns a numeric tag of any type as a float64. Bytes count as signed, as they do in the game.
+func nbtNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case byte:
+		return float64(int8(v)), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func (q Query) matchesCompound(compound NBTCompound) bool {
+	if matched, _ := path.Match(q.id, compound.ID()); !matched {
+		return false
+	}
+	return matchConditions(map[string]interface{}(compound), q.conditions)
+}
+
+// FindResult is something a query found. Blocks and tile entities are at whole coordinates; entities are wherever
+// they stand.
+type FindResult struct {
+	Query string  `json:"query"`
+	Kind  string  `json:"kind"`
+	Name  string  `json:"name"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Z     float64 `json:"z"`
+}
+
+// Find returns everything in the world that matches any of the queries, sorted by position. Block state patterns
+// also find legacy blocks, by the name 1.13 gives them.
+func (world *AnvilWorld) Find(queries []Query) []FindResult {
+	var results []FindResult
+	legacyNames := make([]BlockState, 256*16)
+	for i := range legacyNames {
+		legacyNames[i] = flattenLegacyBlock(byte(i>>4), byte(i&15))
+	}
+
+	for _, coord := range world.getSlimeSortedChunkKeys() {
+		chunk := world.chunks[coord]
+		for _, query := range queries {
+			switch query.kind {
+			case FindBlock:
+				results = append(results, findBlocks(chunk, query, legacyNames)...)
+			case FindTileEntity:
+				for _, tileEntity := range chunk.TileEntities {
+					if query.matchesCompound(tileEntity) {
+						x, _ := tileEntity.Int("x")
+						y, _ := tileEntity.Int("y")
+						z, _ := tileEntity.Int("z")
+						results = append(results, FindResult{Query: query.Text, Kind: FindTileEntity,
+							Name: tileEntity.ID(), X: float64(x), Y: float64(y), Z: float64(z)})
+					}
+				}
+			case FindEntity:
+				for _, entity := range chunk.Entities {
+					if query.matchesCompound(entity) {
+						result := FindResult{Query: query.Text, Kind: FindEntity, Name: entity.ID()}
+						if pos, ok := entity["Pos"].([]interface{}); ok && len(pos) == 3 {
+							result.X, _ = nbtNumber(pos[0])
+							result.Y, _ = nbtNumber(pos[1])
+							result.Z, _ = nbtNumber(pos[2])
+						}
+						results = append(results, result)
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		if a.Z != b.Z {
+			return a.Z < b.Z
+		}
+		return a.X < b.X
+	})
+	return results
+}
+
+func findBlocks(chunk MinecraftChunk, query Query, legacyNames []BlockState) []FindResult {
+	var results []FindResult
+	add := func(idx int, section MinecraftChunkSection, state BlockState) {
+		results = append(results, FindResult{
+			Query: query.Text,
+			Kind:  FindBlock,
+			Name:  state.String(),
+			X:     float64(chunk.X*16 + idx&15),
+			Y:     float64(int(section.Y)*16 + idx>>8),
+			Z:     float64(chunk.Z*16 + idx>>4&15),
+		})
+	}
+
+	for _, section := range chunk.Sections {
+		if section.usesPalette() {
+			matching := make([]bool, len(section.Palette))
+			palette := make([]BlockState, len(section.Palette))
+			anyMatching := false
+			for p, entry := range section.Palette {
+				palette[p] = blockStateFromPaletteEntry(entry)
+				matching[p] = query.block.matchesState(palette[p])
+				anyMatching = anyMatching || matching[p]
+			}
+			if !anyMatching {
+				continue
+			}
+			for idx, p := range unpackBlockStates(section.BlockStates, len(section.BlockStates)/64, 4096) {
+				if p < len(matching) && matching[p] {
+					add(idx, section, palette[p])
+				}
+			}
+			continue
+		}
+
+		for idx, id := range section.Blocks {
+			data := getNibble(section.Data, idx)
+			state := legacyNames[int(id)<<4|int(data)]
+			if query.block.matchesLegacy(id, data) || query.block.matchesState(state) {
+				add(idx, section, state)
+			}
+		}
+	}
+	return results
+}
+
+// formatFindPosition writes whole coordinates without decimals, and others with two.
+func formatFindPosition(result FindResult) string {
+	if result.X == math.Trunc(result.X) && result.Y == math.Trunc(result.Y) && result.Z == math.Trunc(result.Z) {
+		return fmt.Sprintf("%d %d %d", int(result.X), int(result.Y), int(result.Z))
+	}
+	return fmt.Sprintf("%.2f %.2f %.2f", result.X, result.Y, result.Z)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Exit statuses of the find command, which follow grep(1).
+const (
+	exitNothingFound = 1
+	exitFindTrouble  = 2
+)
+
+var findCommand = &cli.Command{
+	Name:      "find",
+	Usage:     "finds blocks, tile entities and entities in a world",
+	ArgsUsage: "WORLD QUERY...",
+	Description: "WORLD is an Anvil world directory or a Slime file. Each QUERY is one of\n\n" +
+		"   block PATTERN\n" +
+		"   tile [ID] [where CONDITION [and CONDITION ...]]\n" +
+		"   entity [ID] [where CONDITION [and CONDITION ...]]\n\n" +
+		"where PATTERN is a block id such as 137 or a block state such as minecraft:*_command_block[conditional=true], " +
+		"ID is an id that may use glob patterns, and CONDITION is an NBT path, optionally compared with ==, !=, <, <=, " +
+		">, >= or contains to a quoted string or a number, as in\n\n" +
+		"   tile minecraft:chest where Items[].id == \"minecraft:diamond\" and Items[].Count > 10\n\n" +
+		"Conditions that pick list entries with [] at the same place in their paths must hold for the same entry, so " +
+		"this finds a stack of more than 10 diamonds. Everything that matches any query is listed. The command exits with status 0 if it found anything, 1 if it " +
+		"did not and 2 if the search failed.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "lists results as `FORMAT` (text or json)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() < 2 {
+			return exitStatusError{status: exitFindTrouble, err: errors.New("need a world and at least one query")}
+		}
+		var printResults func(io.Writer, []FindResult) error
+		switch format := c.String("format"); format {
+		case "text":
+			printResults = printFindResultsText
+		case "json":
+			printResults = printFindResultsJSON
+		default:
+			return exitStatusError{status: exitFindTrouble,
+				err: fmt.Errorf("unknown result format %q (expected text or json)", format)}
+		}
+
+		var queries []Query
+		for _, text := range c.Args().Slice()[1:] {
+			query, err := ParseQuery(text)
+			if err != nil {
+				return exitStatusError{status: exitFindTrouble, err: err}
+			}
+			queries = append(queries, query)
+		}
+
+		results, err := findInWorld(c.Context, c.Args().First(), queries, anvilReadOptionsFromFlags(c))
+		if err != nil {
+			return exitStatusError{status: exitFindTrouble, err: err}
+		}
+		if err = printResults(os.Stdout, results); err != nil {
+			return exitStatusError{status: exitFindTrouble, err: err}
+		}
+		if len(results) == 0 {
+			return exitStatusError{status: exitNothingFound}
+		}
+		return nil
+	},
+}
+
+func findInWorld(ctx context.Context, path string, queries []Query, options AnvilReadOptions) ([]FindResult,
+	error) {
+	world, err := OpenWorld(ctx, path, options, newProgressReporter())
+	if err != nil {
+		return nil, err
+	}
+	results := world.Find(queries)
+	logger.Info("searched world", "results", len(results))
+	return results, nil
+}
+
+// printFindResultsText writes one result per line, as X Y Z KIND NAME.
+func printFindResultsText(out io.Writer, results []FindResult) error {
+	w := &errWriter{out: out}
+	for _, result := range results {
+		w.printf("%s %s %s\n", formatFindPosition(result), result.Kind, result.Name)
+	}
+	return w.err
+}
+
+func printFindResultsJSON(out io.Writer, results []FindResult) error {
+	if results == nil {
+		results = []FindResult{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(results)
+}
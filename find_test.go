@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestParseQuery(t *testing.T) {
+	for _, valid := range []string{
+		"block 137",
+		"block minecraft:*_command_block[conditional=true]",
+		"tile",
+		"tile minecraft:sign where Text1 contains \"spawn\"",
+		"tile where Items[].id == \"minecraft:diamond\" and Items[0].Count>=2",
+		"entity minecraft:* where CustomName",
+	} {
+		if _, err := ParseQuery(valid); err != nil {
+			t.Errorf("%q: %v", valid, err)
+		}
+	}
+
+	for _, invalid := range []string{
+		"",
+		"blocks 1",
+		"block",
+		"block 300",
+		"tile minecraft:chest Items",
+		"tile where",
+		"tile where Items[.id",
+		"tile where Count = 1",
+		"tile where Count == ",
+		"tile where Count contains 1",
+		"tile where Text1 == \"unterminated",
+		"tile where x == 1 y == 2",
+	} {
+		if _, err := ParseQuery(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	world := newTestWorld()
+	chunk := world.chunks[ChunkCoord{X: 1, Z: -1}]
+	chunk.Sections[0].Blocks[2<<8|3<<4|4] = 137
+	chunk.TileEntities = append(chunk.TileEntities, NBTCompound{
+		"id": "minecraft:chest", "x": int32(20), "y": int32(2), "z": int32(-13),
+		"Items": []interface{}{
+			map[string]interface{}{"id": "minecraft:dirt", "Count": byte(64)},
+			map[string]interface{}{"id": "minecraft:diamond", "Count": byte(2)},
+		},
+	})
+	world.chunks[ChunkCoord{X: 1, Z: -1}] = chunk
+
+	for text, expected := range map[string][]FindResult{
+		"block 137": {{Kind: FindBlock, Name: "minecraft:command_block[conditional=false,facing=down]", X: 20, Y: 2,
+			Z: -13}},
+		"block minecraft:command_block": {{Kind: FindBlock, Name: "minecraft:command_block[conditional=false,facing=down]",
+			X: 20, Y: 2, Z: -13}},
+		`tile where Items[].id == "minecraft:diamond"`: {{Kind: FindTileEntity, Name: "minecraft:chest", X: 20, Y: 2,
+			Z: -13}},
+		`tile where Items[].id == "minecraft:diamond" and Items[].Count >= 2`: {{Kind: FindTileEntity,
+			Name: "minecraft:chest", X: 20, Y: 2, Z: -13}},
+		`entity minecraft:p?g where Pos[0] == 32.5 and Pos[2] == 0.5`: {{Kind: FindEntity, Name: "minecraft:pig",
+			X: 32.5, Y: 1, Z: 0.5}},
+	} {
+		query, err := ParseQuery(text)
+		if err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+		results := world.Find([]Query{query})
+		if len(results) != len(expected) {
+			t.Errorf("%q: expected %d results, got %+v", text, len(expected), results)
+			continue
+		}
+		for i := range expected {
+			expected[i].Query = text
+			if results[i] != expected[i] {
+				t.Errorf("%q: expected %+v, got %+v", text, expected[i], results[i])
+			}
+		}
+	}
+
+	for text, count := range map[string]int{
+		`tile where Items[1].Count > 10`:                                      0,
+		`tile where Items[].id == "minecraft:diamond" and Items[].Count > 10`: 0,
+		`tile where Items[].id == "minecraft:dirt" and Items[1].Count == 2`:   1,
+		`tile minecraft:sign where CustomName`:                                24,
+		`entity minecraft:pig where Health < 5`:                               0,
+		`tile minecraft:sign where Text1 contains "ell"`:                      24,
+		`tile minecraft:sign where Text1 != "hello"`:                          0,
+		"block minecraft:stone":                                               24 * 256,
+	} {
+		query, err := ParseQuery(text)
+		if err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+		if results := world.Find([]Query{query}); len(results) != count {
+			t.Errorf("%q: expected %d results, got %d", text, count, len(results))
+		}
+	}
+}
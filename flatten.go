@@ -0,0 +1,240 @@
+package main
+
+import "strings"
+
+// Flatten converts the legacy block ids of every section in the world to block states, as 1.13 did when it loaded an
+// older world. Properties that legacy worlds kept in tile entities, such as the color of beds and banners, the type of
+// skulls and the plant in a flower pot, move into the block state, and tile entities that 1.13 no longer has are
+// removed. Scheduled ticks are renamed after the new blocks. Sections that already use a palette are left alone.
+func (world *AnvilWorld) Flatten() {
+	for coord, chunk := range world.chunks {
+		flattenChunk(&chunk)
+		world.chunks[coord] = chunk
+	}
+}
+
+// IsFlattened reports whether any section of the world stores block states rather than legacy block ids.
+func (world *AnvilWorld) IsFlattened() bool {
+	for _, chunk := range world.chunks {
+		for _, section := range chunk.Sections {
+			if section.usesPalette() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type blockPos struct {
+	x, y, z int
+}
+
+// legacyChunk looks up legacy blocks and tile entities by their position in a chunk.
+type legacyChunk struct {
+	sections     map[int]*MinecraftChunkSection
+	tileEntities map[blockPos]NBTCompound
+	originX      int
+	originZ      int
+}
+
+func (c *legacyChunk) blockAt(x, y, z int) (id, data byte) {
+	section, ok := c.sections[y>>4]
+	if !ok || y < 0 {
+		return 0, 0
+	}
+	idx := (y&15)<<8 | z<<4 | x
+	return section.Blocks[idx], getNibble(section.Data, idx)
+}
+
+func (c *legacyChunk) tileEntityAt(x, y, z int) NBTCompound {
+	return c.tileEntities[blockPos{x: c.originX + x, y: y, z: c.originZ + z}]
+}
+
+func flattenChunk(chunk *MinecraftChunk) {
+	legacy := &legacyChunk{
+		sections:     make(map[int]*MinecraftChunkSection),
+		tileEntities: make(map[blockPos]NBTCompound),
+		originX:      chunk.X * 16,
+		originZ:      chunk.Z * 16,
+	}
+	for i := range chunk.Sections {
+		if section := &chunk.Sections[i]; !section.usesPalette() {
+			legacy.sections[int(section.Y)] = section
+		}
+	}
+	if len(legacy.sections) == 0 {
+		return
+	}
+	for _, tileEntity := range chunk.TileEntities {
+		x, okX := tileEntity.Int("x")
+		y, okY := tileEntity.Int("y")
+		z, okZ := tileEntity.Int("z")
+		if okX && okY && okZ {
+			legacy.tileEntities[blockPos{x: x, y: y, z: z}] = tileEntity
+		}
+	}
+
+	// Every section is converted before any is replaced, since blocks may depend on blocks in another section.
+	converted := make(map[int][]BlockState, len(legacy.sections))
+	removed := make(map[blockPos]bool)
+	for sectionY, section := range legacy.sections {
+		states := make([]BlockState, 4096)
+		for idx := range states {
+			x, y, z := idx&15, sectionY<<4|idx>>8, idx>>4&15
+			id, data := section.Blocks[idx], getNibble(section.Data, idx)
+			var removeTileEntity bool
+			states[idx], removeTileEntity = flattenBlockInChunk(legacy, x, y, z, id, data)
+			if removeTileEntity {
+				removed[blockPos{x: legacy.originX + x, y: y, z: legacy.originZ + z}] = true
+			}
+		}
+		converted[sectionY] = states
+	}
+	flattenTicks(chunk, legacy, converted)
+	for sectionY, states := range converted {
+		legacy.sections[sectionY].setBlockStates(states)
+	}
+
+	if len(removed) > 0 {
+		var kept []NBTCompound
+		for _, tileEntity := range chunk.TileEntities {
+			x, _ := tileEntity.Int("x")
+			y, _ := tileEntity.Int("y")
+			z, _ := tileEntity.Int("z")
+			if !removed[blockPos{x: x, y: y, z: z}] {
+				kept = append(kept, tileEntity)
+			}
+		}
+		chunk.TileEntities = kept
+	}
+}
+
+var doorIDs = map[byte]bool{64: true, 71: true, 193: true, 194: true, 195: true, 196: true, 197: true}
+
+// flattenBlockInChunk converts a block using the blocks around it and its tile entity where the legacy format kept
+// part of its state there. It also reports whether 1.13 drops the block's tile entity.
+func flattenBlockInChunk(c *legacyChunk, x, y, z int, id, data byte) (BlockState, bool) {
+	state := flattenLegacyBlock(id, data)
+	switch {
+	case doorIDs[id]:
+		if data&8 != 0 {
+			if belowID, belowData := c.blockAt(x, y-1, z); belowID == id && belowData&8 == 0 {
+				state = state.with("facing", facingESWN[belowData&3]).with("open", boolString(belowData&4 != 0))
+			}
+		} else if aboveID, aboveData := c.blockAt(x, y+1, z); aboveID == id && aboveData&8 != 0 {
+			hinge := "left"
+			if aboveData&1 != 0 {
+				hinge = "right"
+			}
+			state = state.with("hinge", hinge).with("powered", boolString(aboveData&2 != 0))
+		}
+
+	case id == 175 && data&8 != 0:
+		if belowID, belowData := c.blockAt(x, y-1, z); belowID == 175 {
+			state.Name = flattenLegacyBlock(belowID, belowData&7).Name
+		}
+
+	case id == 26:
+		if tileEntity := c.tileEntityAt(x, y, z); tileEntity != nil {
+			if color, ok := tileEntity.Int("color"); ok {
+				state.Name = "minecraft:" + legacyColors[color&15] + "_bed"
+			}
+		}
+
+	case id == 176 || id == 177:
+		// Banners store their base color as a dye, which counts the colors the other way around.
+		base := 0
+		if tileEntity := c.tileEntityAt(x, y, z); tileEntity != nil {
+			base, _ = tileEntity.Int("Base")
+		}
+		suffix := "_banner"
+		if id == 177 {
+			suffix = "_wall_banner"
+		}
+		state.Name = "minecraft:" + legacyColors[15-(base&15)] + suffix
+
+	case id == 144:
+		if tileEntity := c.tileEntityAt(x, y, z); tileEntity != nil {
+			state = flattenSkull(state, tileEntity)
+		}
+
+	case id == 140:
+		if tileEntity := c.tileEntityAt(x, y, z); tileEntity != nil {
+			state = flattenFlowerPot(tileEntity)
+		}
+		return state, true
+
+	case id == 25:
+		if tileEntity := c.tileEntityAt(x, y, z); tileEntity != nil {
+			if note, ok := tileEntity.Int("note"); ok {
+				state = state.with("note", intString(byte(note%25)))
+			}
+		}
+		return state, true
+	}
+	return state, false
+}
+
+var skullNames = [6][2]string{
+	{"skeleton_skull", "skeleton_wall_skull"},
+	{"wither_skeleton_skull", "wither_skeleton_wall_skull"},
+	{"zombie_head", "zombie_wall_head"},
+	{"player_head", "player_wall_head"},
+	{"creeper_head", "creeper_wall_head"},
+	{"dragon_head", "dragon_wall_head"},
+}
+
+func flattenSkull(state BlockState, tileEntity NBTCompound) BlockState {
+	skullType, _ := tileEntity.Int("SkullType")
+	if skullType < 0 || skullType >= len(skullNames) {
+		skullType = 0
+	}
+	if _, onWall := state.Properties["facing"]; onWall {
+		state.Name = "minecraft:" + skullNames[skullType][1]
+		return state
+	}
+	rotation, _ := tileEntity.Int("Rot")
+	return blockState(skullNames[skullType][0], "rotation", intString(byte(rotation&15)))
+}
+
+// legacyPlantItems maps the item ids that flower pots used to hold to the block ids of the plants.
+var legacyPlantItems = map[string]byte{
+	"minecraft:sapling":        6,
+	"minecraft:tallgrass":      31,
+	"minecraft:deadbush":       32,
+	"minecraft:yellow_flower":  37,
+	"minecraft:red_flower":     38,
+	"minecraft:brown_mushroom": 39,
+	"minecraft:red_mushroom":   40,
+	"minecraft:cactus":         81,
+}
+
+// pottablePlants are the plants that 1.13 has a potted block for.
+var pottablePlants = map[string]bool{
+	"oak_sapling": true, "spruce_sapling": true, "birch_sapling": true, "jungle_sapling": true,
+	"acacia_sapling": true, "dark_oak_sapling": true, "fern": true, "dead_bush": true, "dandelion": true,
+	"poppy": true, "blue_orchid": true, "allium": true, "azure_bluet": true, "red_tulip": true, "orange_tulip": true,
+	"white_tulip": true, "pink_tulip": true, "oxeye_daisy": true, "brown_mushroom": true, "red_mushroom": true,
+	"cactus": true,
+}
+
+func flattenFlowerPot(tileEntity NBTCompound) BlockState {
+	var id byte
+	switch item := tileEntity["Item"].(type) {
+	case string:
+		id = legacyPlantItems[item]
+	default:
+		if numeric, ok := tileEntity.Int("Item"); ok && numeric > 0 && numeric < 256 {
+			id = byte(numeric)
+		}
+	}
+	data, _ := tileEntity.Int("Data")
+	if id == 0 {
+		return blockState("flower_pot")
+	}
+	plant := strings.TrimPrefix(flattenLegacyBlock(id, byte(data)).Name, "minecraft:")
+	if !pottablePlants[plant] {
+		return blockState("flower_pot")
+	}
+	return blockState("potted_" + plant)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPackBlockStates_roundTrip(t *testing.T) {
+	for _, bits := range []int{4, 5, 7, 12} {
+		indexes := make([]int, 4096)
+		for i := range indexes {
+			indexes[i] = (i * 7919) % (1 << uint(bits))
+		}
+		packed := packBlockStates(indexes, bits)
+		if len(packed) != bits*64 {
+			t.Errorf("%d bits: packed into %d longs, want %d", bits, len(packed), bits*64)
+		}
+		if unpacked := unpackBlockStates(packed, bits, len(indexes)); !reflect.DeepEqual(indexes, unpacked) {
+			t.Errorf("%d bits: indexes changed after packing", bits)
+		}
+	}
+}
+
+func TestParseBlockState(t *testing.T) {
+	state, err := ParseBlockState("oak_stairs[half=top,facing=east]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.String() != "minecraft:oak_stairs[facing=east,half=top]" {
+		t.Errorf("unexpected state %s", state)
+	}
+	for _, invalid := range []string{"", "[facing=east]", "stone[facing]", "stone[facing=east"} {
+		if _, err = ParseBlockState(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	section := MinecraftChunkSection{Y: 0, Blocks: make([]byte, 4096), Data: make([]byte, 2048),
+		BlockLight: make([]byte, 2048), SkyLight: make([]byte, 2048)}
+	set := func(x, y, z int, id, data byte) {
+		idx := y<<8 | z<<4 | x
+		section.Blocks[idx] = id
+		setNibble(section.Data, idx, data)
+	}
+	set(0, 1, 0, 53, 5)  // oak stairs facing west, upside down
+	set(1, 1, 0, 64, 1)  // lower half of a door facing south
+	set(1, 2, 0, 64, 9)  // upper half with its hinge on the right
+	set(2, 1, 0, 26, 10) // head of a bed facing north
+	set(3, 1, 0, 140, 0) // flower pot
+	set(4, 1, 0, 144, 3) // skull on a wall facing south
+	set(5, 1, 0, 175, 4) // lower half of a rose bush
+	set(5, 2, 0, 175, 8) // upper half
+	set(6, 1, 0, 35, 14) // red wool
+
+	chunk := MinecraftChunk{
+		X: 1, Z: 0,
+		Sections: []MinecraftChunkSection{section},
+		TileEntities: []NBTCompound{
+			{"id": "minecraft:bed", "x": int32(18), "y": int32(1), "z": int32(0), "color": int32(11)},
+			{"id": "minecraft:flower_pot", "x": int32(19), "y": int32(1), "z": int32(0),
+				"Item": "minecraft:red_flower", "Data": int32(2)},
+			{"id": "minecraft:skull", "x": int32(20), "y": int32(1), "z": int32(0), "SkullType": byte(4)},
+		},
+	}
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{X: 1, Z: 0}: chunk}}
+	world.Flatten()
+	flattened := world.chunks[ChunkCoord{X: 1, Z: 0}]
+
+	states := flattened.Sections[0].blockStates()
+	for _, test := range []struct {
+		x, y, z  int
+		expected string
+	}{
+		{0, 1, 0, "minecraft:oak_stairs[facing=west,half=top,shape=straight,waterlogged=false]"},
+		{1, 1, 0, "minecraft:oak_door[facing=south,half=lower,hinge=right,open=false,powered=false]"},
+		{1, 2, 0, "minecraft:oak_door[facing=south,half=upper,hinge=right,open=false,powered=false]"},
+		{2, 1, 0, "minecraft:blue_bed[facing=north,occupied=false,part=head]"},
+		{3, 1, 0, "minecraft:potted_allium"},
+		{4, 1, 0, "minecraft:creeper_wall_head[facing=south]"},
+		{5, 2, 0, "minecraft:rose_bush[half=upper]"},
+		{6, 1, 0, "minecraft:red_wool"},
+		{7, 7, 7, "minecraft:air"},
+	} {
+		if state := states[test.y<<8|test.z<<4|test.x].String(); state != test.expected {
+			t.Errorf("block at %d,%d,%d: expected %s, got %s", test.x, test.y, test.z, test.expected, state)
+		}
+	}
+
+	if len(flattened.TileEntities) != 2 {
+		t.Errorf("expected the flower pot's tile entity to be removed, have %d tile entities",
+			len(flattened.TileEntities))
+	}
+	if flattened.Sections[0].Blocks != nil || flattened.Sections[0].Data != nil {
+		t.Error("legacy blocks were kept")
+	}
+
+	var buf bytes.Buffer
+	if err := world.WriteAsSlime(context.Background(), &buf, SlimeWriteOptions{Version: 3}); err == nil {
+		t.Error("expected Slime version 3 to refuse a flattened world")
+	}
+	buf.Reset()
+	if err := world.WriteAsSlime(context.Background(), &buf, SlimeWriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSlimeWorld(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readStates := read.chunks[ChunkCoord{X: 1, Z: 0}].Sections[0].blockStates()
+	for i := range states {
+		if states[i].String() != readStates[i].String() {
+			t.Fatalf("block %d changed from %s to %s after writing and reading", i, states[i], readStates[i])
+		}
+	}
+}
@@ -4,6 +4,6 @@ go 1.13
 
 require (
 	github.com/google/go-cmp v0.3.1 // indirect
-	github.com/klauspost/compress v1.9.4
+	github.com/klauspost/compress v1.11.13
 	github.com/urfave/cli/v2 v2.0.0
 )
@@ -0,0 +1,99 @@
+package main
+
+import "sort"
+
+// Height maps that worlds from 1.13 on keep in their Heightmaps compound, which stand in for the height map of older
+// worlds. 1.13 keeps LIGHT_BLOCKING, which it replaced with MOTION_BLOCKING in 1.14.
+const (
+	heightMapLightBlocking  = "LIGHT_BLOCKING"
+	heightMapMotionBlocking = "MOTION_BLOCKING"
+)
+
+// heightMapBits is how many bits Slime worlds pack each column of a named height map into, enough for heights from
+// 0 to 256.
+const heightMapBits = 9
+
+// heightMapBitsFor returns how many bits each column of a packed height map of the given length takes, preferring
+// the fewest bits if more than one fits.
+func heightMapBitsFor(length int, padded bool) (int, bool) {
+	for bits := 1; bits <= 32; bits++ {
+		longs := (256*bits + 63) / 64
+		if padded {
+			perLong := 64 / bits
+			longs = (256 + perLong - 1) / perLong
+		}
+		if longs == length {
+			return bits, true
+		}
+	}
+	return 0, false
+}
+
+// decodeHeightMaps unpacks the height maps of a Heightmaps compound, which are packed the way block states are and
+// count from minY, the bottom of the world. Heights are moved to count from 0 and limited to the 256 blocks a Slime
+// world is high. Height maps that cannot be unpacked are left out for the server to calculate.
+func decodeHeightMaps(compound NBTCompound, padded bool, minY int) map[string][]int {
+	var heightMaps map[string][]int
+	for name, value := range compound {
+		packed, ok := value.([]int64)
+		if !ok {
+			continue
+		}
+		bits, ok := heightMapBitsFor(len(packed), padded)
+		if !ok {
+			continue
+		}
+		var heights []int
+		if padded {
+			heights = unpackPaddedIndexes(packed, bits, 256)
+		} else {
+			heights = unpackBlockStates(packed, bits, 256)
+		}
+		for i, height := range heights {
+			if height += minY; height < 0 {
+				height = 0
+			} else if height > 256 {
+				height = 256
+			}
+			heights[i] = height
+		}
+		if heightMaps == nil {
+			heightMaps = make(map[string][]int)
+		}
+		heightMaps[name] = heights
+	}
+	return heightMaps
+}
+
+// encodeHeightMaps packs height maps into a Heightmaps compound the way 1.13 through 1.15 do.
+func encodeHeightMaps(heightMaps map[string][]int) NBTCompound {
+	compound := make(NBTCompound, len(heightMaps))
+	for name, heights := range heightMaps {
+		compound[name] = packBlockStates(heights, heightMapBits)
+	}
+	return compound
+}
+
+// legacyHeightMap returns the height map worlds before 1.13 store for a chunk: its own if it has one, or else one
+// taken from its named height maps, or else one calculated from its blocks.
+func (chunk MinecraftChunk) legacyHeightMap() []int {
+	if len(chunk.HeightMap) == 256 {
+		return chunk.HeightMap
+	}
+	for _, name := range []string{heightMapLightBlocking, heightMapMotionBlocking} {
+		if heights := chunk.HeightMaps[name]; len(heights) == 256 {
+			return heights
+		}
+	}
+	return computeHeightMap(chunk)
+}
+
+// heightMapNames returns the names of the named height maps of a chunk in order.
+func (chunk MinecraftChunk) heightMapNames() []string {
+	names := make([]string, 0, len(chunk.HeightMaps))
+	for name := range chunk.HeightMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
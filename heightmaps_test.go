@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestDecodeHeightMaps(t *testing.T) {
+	heights := make([]int, 256)
+	for i := range heights {
+		heights[i] = i + 64
+	}
+	heightMaps := decodeHeightMaps(NBTCompound{
+		"MOTION_BLOCKING": packPadded(heights, 9),
+		"WORLD_SURFACE":   packBlockStates(heights, 9),
+		"OCEAN_FLOOR":     make([]int64, 5),
+	}, true, -64)
+	if len(heightMaps) != 1 {
+		t.Fatalf("expected only MOTION_BLOCKING to be unpacked, got %d height maps", len(heightMaps))
+	}
+	for i, height := range heightMaps["MOTION_BLOCKING"] {
+		expected := i
+		if expected > 256 {
+			expected = 256
+		}
+		if height != expected {
+			t.Fatalf("column %d: expected height %d, got %d", i, expected, height)
+		}
+	}
+
+	heightMaps = decodeHeightMaps(NBTCompound{"WORLD_SURFACE": packBlockStates(heights, 9)}, false, 0)
+	if surface := heightMaps["WORLD_SURFACE"]; surface[0] != 64 || surface[192] != 256 {
+		t.Errorf("unexpected spanning height map starting with %v", surface[:4])
+	}
+}
+
+func TestLegacyHeightMap(t *testing.T) {
+	chunk := newTestChunk(0, 0)
+	chunk.HeightMap = nil
+	if heights := chunk.legacyHeightMap(); heights[0] != 1 {
+		t.Errorf("expected a height map calculated from the blocks, got %d", heights[0])
+	}
+
+	motionBlocking := make([]int, 256)
+	motionBlocking[0] = 70
+	chunk.HeightMaps = map[string][]int{heightMapMotionBlocking: motionBlocking}
+	if heights := chunk.legacyHeightMap(); heights[0] != 70 {
+		t.Errorf("expected the height map to be taken from MOTION_BLOCKING, got %d", heights[0])
+	}
+}
+
+func TestWriteAsSlime_heightMaps(t *testing.T) {
+	world := newTestWorld()
+	world.Flatten()
+	motionBlocking := make([]int, 256)
+	for i := range motionBlocking {
+		motionBlocking[i] = i
+	}
+	chunk := world.chunks[ChunkCoord{X: 1, Z: 1}]
+	chunk.HeightMaps = map[string][]int{heightMapMotionBlocking: motionBlocking, "WORLD_SURFACE": make([]int, 256)}
+	world.chunks[ChunkCoord{X: 1, Z: 1}] = chunk
+
+	readBack := writeAndReadBack(t, world, SlimeWriteOptions{})
+	heightMaps := readBack.chunks[ChunkCoord{X: 1, Z: 1}].HeightMaps
+	if len(heightMaps) != 2 || heightMaps[heightMapMotionBlocking][255] != 255 {
+		t.Errorf("expected both height maps to be read back, got %v", heightMaps)
+	}
+	if other := readBack.chunks[ChunkCoord{}].HeightMaps; other != nil {
+		t.Errorf("expected chunks without height maps to be written without them, got %v", other)
+	}
+	if err := world.VerifySlime(readBack, SlimeWriteOptions{}); err != nil {
+		t.Error(err)
+	}
+}
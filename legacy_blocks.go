@@ -0,0 +1,689 @@
+package main
+
+import "strconv"
+
+// legacyColors are the sixteen colors in the order legacy data values use for wool, glass, clay, carpet and concrete.
+var legacyColors = [16]string{
+	"white", "orange", "magenta", "light_blue", "yellow", "lime", "pink", "gray",
+	"light_gray", "cyan", "purple", "blue", "brown", "green", "red", "black",
+}
+
+// legacyWoods are the wood types in the order legacy data values use for planks, saplings, slabs and the like.
+var legacyWoods = [6]string{"oak", "spruce", "birch", "jungle", "acacia", "dark_oak"}
+
+// Legacy blocks store directions in several orders. Each table maps the relevant bits of the data value to a facing.
+var (
+	facingDUNSWE = [8]string{"down", "up", "north", "south", "west", "east", "down", "up"}
+	facingSWNE   = [4]string{"south", "west", "north", "east"}
+	facingNSWE   = [4]string{"north", "south", "west", "east"}
+	facingEWSN   = [4]string{"east", "west", "south", "north"}
+	facingESWN   = [4]string{"east", "south", "west", "north"}
+)
+
+// facing2to5 handles blocks that store a facing as 2 to 5, the same as facingDUNSWE, falling back to north.
+func facing2to5(data byte) string {
+	if data < 2 || data > 5 {
+		return "north"
+	}
+	return facingDUNSWE[data]
+}
+
+func boolString(b bool) string {
+	return strconv.FormatBool(b)
+}
+
+func intString(i byte) string {
+	return strconv.Itoa(int(i))
+}
+
+// legacyAxis maps the axis bits used by logs, hay, bone blocks and purpur pillars.
+func legacyAxis(data byte) string {
+	switch data & 0xc {
+	case 0x4:
+		return "x"
+	case 0x8:
+		return "z"
+	default:
+		return "y"
+	}
+}
+
+// byData picks a state by the data value, falling back to the first one like the game does for unknown values.
+func byData(states ...BlockState) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		if int(data) < len(states) {
+			return states[data]
+		}
+		return states[0]
+	}
+}
+
+func simple(name string, properties ...string) func(data byte) BlockState {
+	state := blockState(name, properties...)
+	return func(byte) BlockState { return state }
+}
+
+func colored(suffix string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(legacyColors[data&15] + "_" + suffix)
+	}
+}
+
+func stairs(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		half := "bottom"
+		if data&4 != 0 {
+			half = "top"
+		}
+		return blockState(name, "facing", facingEWSN[data&3], "half", half, "shape", "straight", "waterlogged", "false")
+	}
+}
+
+func slab(names [8]string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		half := "bottom"
+		if data&8 != 0 {
+			half = "top"
+		}
+		name := names[data&7]
+		if name == "" {
+			name = names[0]
+		}
+		return blockState(name, "type", half, "waterlogged", "false")
+	}
+}
+
+func doubleSlab(names [8]string, smooth map[byte]string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		if name, ok := smooth[data]; ok {
+			return blockState(name)
+		}
+		name := names[data&7]
+		if name == "" {
+			name = names[0]
+		}
+		return blockState(name, "type", "double", "waterlogged", "false")
+	}
+}
+
+var stoneSlabs = [8]string{"stone_slab", "sandstone_slab", "petrified_oak_slab", "cobblestone_slab", "brick_slab",
+	"stone_brick_slab", "nether_brick_slab", "quartz_slab"}
+
+var woodenSlabs = [8]string{"oak_slab", "spruce_slab", "birch_slab", "jungle_slab", "acacia_slab", "dark_oak_slab"}
+
+var redSandstoneSlabs = [8]string{"red_sandstone_slab"}
+
+var purpurSlabs = [8]string{"purpur_slab"}
+
+func fence(name string) func(data byte) BlockState {
+	return simple(name, "east", "false", "north", "false", "south", "false", "waterlogged", "false", "west", "false")
+}
+
+func fenceGate(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(name, "facing", facingSWNE[data&3], "in_wall", "false", "open", boolString(data&4 != 0),
+			"powered", "false")
+	}
+}
+
+// door returns the state of one half of a door. Legacy doors split their properties between the halves, so
+// flattenChunk fills in the rest from the other half.
+func door(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		if data&8 != 0 {
+			hinge := "left"
+			if data&1 != 0 {
+				hinge = "right"
+			}
+			return blockState(name, "half", "upper", "hinge", hinge, "powered", boolString(data&2 != 0),
+				"facing", "east", "open", "false")
+		}
+		return blockState(name, "half", "lower", "facing", facingESWN[data&3], "open", boolString(data&4 != 0),
+			"hinge", "left", "powered", "false")
+	}
+}
+
+func trapdoor(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		half := "bottom"
+		if data&8 != 0 {
+			half = "top"
+		}
+		return blockState(name, "facing", facingNSWE[data&3], "half", half, "open", boolString(data&4 != 0),
+			"powered", "false", "waterlogged", "false")
+	}
+}
+
+func torch(name, wallName string, properties ...string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		switch data {
+		case 1, 2, 3, 4:
+			return blockState(wallName, append([]string{"facing", facingEWSN[(data-1)&3]}, properties...)...)
+		default:
+			return blockState(name, properties...)
+		}
+	}
+}
+
+func button(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		powered := boolString(data&8 != 0)
+		switch data & 7 {
+		case 0:
+			return blockState(name, "face", "ceiling", "facing", "north", "powered", powered)
+		case 5:
+			return blockState(name, "face", "floor", "facing", "north", "powered", powered)
+		default:
+			return blockState(name, "face", "wall", "facing", facingEWSN[(data&7-1)&3], "powered", powered)
+		}
+	}
+}
+
+func rail(name string, powerable bool) func(data byte) BlockState {
+	shapes := []string{"north_south", "east_west", "ascending_east", "ascending_west", "ascending_north",
+		"ascending_south", "south_east", "south_west", "north_west", "north_east"}
+	return func(data byte) BlockState {
+		if !powerable {
+			if int(data) >= len(shapes) {
+				data = 0
+			}
+			return blockState(name, "shape", shapes[data])
+		}
+		shape := data & 7
+		if shape > 5 {
+			shape = 0
+		}
+		return blockState(name, "shape", shapes[shape], "powered", boolString(data&8 != 0))
+	}
+}
+
+func piston(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(name, "facing", facingDUNSWE[data&7], "extended", boolString(data&8 != 0))
+	}
+}
+
+func dispenser(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(name, "facing", facingDUNSWE[data&7], "triggered", boolString(data&8 != 0))
+	}
+}
+
+func horizontalFacing(name string, properties ...string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(name, append([]string{"facing", facing2to5(data)}, properties...)...)
+	}
+}
+
+func axisBlock(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(name, "axis", legacyAxis(data))
+	}
+}
+
+func logBlock(woods []string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		wood := woods[0]
+		if int(data&3) < len(woods) {
+			wood = woods[data&3]
+		}
+		if data&0xc == 0xc {
+			return blockState(wood+"_wood", "axis", "y")
+		}
+		return blockState(wood+"_log", "axis", legacyAxis(data))
+	}
+}
+
+func leaves(woods []string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		wood := woods[0]
+		if int(data&3) < len(woods) {
+			wood = woods[data&3]
+		}
+		return blockState(wood+"_leaves", "distance", "7", "persistent", boolString(data&4 != 0))
+	}
+}
+
+func aged(name string, maxAge byte) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		if data > maxAge {
+			data = maxAge
+		}
+		return blockState(name, "age", intString(data))
+	}
+}
+
+func liquid(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(name, "level", intString(data&15))
+	}
+}
+
+func diode(name string, powered bool) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(name, "facing", facingSWNE[data&3], "delay", intString(data>>2+1), "locked", "false",
+			"powered", boolString(powered))
+	}
+}
+
+func comparator(data byte) BlockState {
+	mode := "compare"
+	if data&4 != 0 {
+		mode = "subtract"
+	}
+	return blockState("comparator", "facing", facingSWNE[data&3], "mode", mode, "powered", boolString(data&8 != 0))
+}
+
+func commandBlock(name string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(name, "facing", facingDUNSWE[data&7], "conditional", boolString(data&8 != 0))
+	}
+}
+
+func mushroomBlock(name string) func(data byte) BlockState {
+	// Which faces of the block show the cap, in the order down, east, north, south, up, west.
+	faces := map[byte][6]bool{
+		0:  {false, false, false, false, false, false},
+		1:  {false, false, true, false, true, true},
+		2:  {false, false, true, false, true, false},
+		3:  {false, true, true, false, true, false},
+		4:  {false, false, false, false, true, true},
+		5:  {false, false, false, false, true, false},
+		6:  {false, true, false, false, true, false},
+		7:  {false, false, false, true, true, true},
+		8:  {false, false, false, true, true, false},
+		9:  {false, true, false, true, true, false},
+		14: {true, true, true, true, true, true},
+	}
+	return func(data byte) BlockState {
+		switch data {
+		case 10:
+			return blockState("mushroom_stem", "down", "false", "east", "true", "north", "true", "south", "true",
+				"up", "false", "west", "true")
+		case 15:
+			return blockState("mushroom_stem", "down", "true", "east", "true", "north", "true", "south", "true",
+				"up", "true", "west", "true")
+		}
+		f, ok := faces[data]
+		if !ok {
+			f = faces[14]
+		}
+		return blockState(name, "down", boolString(f[0]), "east", boolString(f[1]), "north", boolString(f[2]),
+			"south", boolString(f[3]), "up", boolString(f[4]), "west", boolString(f[5]))
+	}
+}
+
+func shulkerBox(color string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(color+"_shulker_box", "facing", facingDUNSWE[data&7])
+	}
+}
+
+func glazedTerracotta(color string) func(data byte) BlockState {
+	return func(data byte) BlockState {
+		return blockState(color+"_glazed_terracotta", "facing", facingSWNE[data&3])
+	}
+}
+
+// legacyBlocks maps every block id of 1.12 to a function that returns the block state for a data value. Properties
+// that the game derives from neighboring blocks, such as fence connections and stair shapes, are left at their
+// defaults; properties stored in tile entities are filled in by flattenChunk.
+var legacyBlocks = map[byte]func(data byte) BlockState{
+	0: simple("air"),
+	1: byData(blockState("stone"), blockState("granite"), blockState("polished_granite"), blockState("diorite"),
+		blockState("polished_diorite"), blockState("andesite"), blockState("polished_andesite")),
+	2: simple("grass_block", "snowy", "false"),
+	3: byData(blockState("dirt"), blockState("coarse_dirt"), blockState("podzol", "snowy", "false")),
+	4: simple("cobblestone"),
+	5: func(data byte) BlockState { return blockState(legacyWoods[data%6] + "_planks") },
+	6: func(data byte) BlockState {
+		return blockState(legacyWoods[(data&7)%6]+"_sapling", "stage", intString(data>>3&1))
+	},
+	7:  simple("bedrock"),
+	8:  liquid("water"),
+	9:  liquid("water"),
+	10: liquid("lava"),
+	11: liquid("lava"),
+	12: byData(blockState("sand"), blockState("red_sand")),
+	13: simple("gravel"),
+	14: simple("gold_ore"),
+	15: simple("iron_ore"),
+	16: simple("coal_ore"),
+	17: logBlock(legacyWoods[:4]),
+	18: leaves(legacyWoods[:4]),
+	19: byData(blockState("sponge"), blockState("wet_sponge")),
+	20: simple("glass"),
+	21: simple("lapis_ore"),
+	22: simple("lapis_block"),
+	23: dispenser("dispenser"),
+	24: byData(blockState("sandstone"), blockState("chiseled_sandstone"), blockState("cut_sandstone")),
+	25: simple("note_block", "instrument", "harp", "note", "0", "powered", "false"),
+	26: func(data byte) BlockState {
+		part := "foot"
+		if data&8 != 0 {
+			part = "head"
+		}
+		return blockState("red_bed", "facing", facingSWNE[data&3], "occupied", boolString(data&4 != 0),
+			"part", part)
+	},
+	27: rail("powered_rail", true),
+	28: rail("detector_rail", true),
+	29: piston("sticky_piston"),
+	30: simple("cobweb"),
+	31: byData(blockState("dead_bush"), blockState("grass"), blockState("fern")),
+	32: simple("dead_bush"),
+	33: piston("piston"),
+	34: func(data byte) BlockState {
+		kind := "normal"
+		if data&8 != 0 {
+			kind = "sticky"
+		}
+		return blockState("piston_head", "facing", facingDUNSWE[data&7], "short", "false", "type", kind)
+	},
+	35: colored("wool"),
+	36: func(data byte) BlockState {
+		kind := "normal"
+		if data&8 != 0 {
+			kind = "sticky"
+		}
+		return blockState("moving_piston", "facing", facingDUNSWE[data&7], "type", kind)
+	},
+	37: simple("dandelion"),
+	38: byData(blockState("poppy"), blockState("blue_orchid"), blockState("allium"), blockState("azure_bluet"),
+		blockState("red_tulip"), blockState("orange_tulip"), blockState("white_tulip"), blockState("pink_tulip"),
+		blockState("oxeye_daisy")),
+	39: simple("brown_mushroom"),
+	40: simple("red_mushroom"),
+	41: simple("gold_block"),
+	42: simple("iron_block"),
+	43: doubleSlab(stoneSlabs, map[byte]string{8: "smooth_stone", 9: "smooth_sandstone", 15: "smooth_quartz"}),
+	44: slab(stoneSlabs),
+	45: simple("bricks"),
+	46: func(data byte) BlockState { return blockState("tnt", "unstable", boolString(data&1 != 0)) },
+	47: simple("bookshelf"),
+	48: simple("mossy_cobblestone"),
+	49: simple("obsidian"),
+	50: torch("torch", "wall_torch"),
+	51: aged("fire", 15),
+	52: simple("spawner"),
+	53: stairs("oak_stairs"),
+	54: horizontalFacing("chest", "type", "single", "waterlogged", "false"),
+	55: func(data byte) BlockState {
+		return blockState("redstone_wire", "power", intString(data&15), "east", "none", "north", "none",
+			"south", "none", "west", "none")
+	},
+	56: simple("diamond_ore"),
+	57: simple("diamond_block"),
+	58: simple("crafting_table"),
+	59: aged("wheat", 7),
+	60: func(data byte) BlockState { return blockState("farmland", "moisture", intString(data&7)) },
+	61: horizontalFacing("furnace", "lit", "false"),
+	62: horizontalFacing("furnace", "lit", "true"),
+	63: func(data byte) BlockState {
+		return blockState("sign", "rotation", intString(data&15), "waterlogged", "false")
+	},
+	64: door("oak_door"),
+	65: horizontalFacing("ladder", "waterlogged", "false"),
+	66: rail("rail", false),
+	67: stairs("cobblestone_stairs"),
+	68: horizontalFacing("wall_sign", "waterlogged", "false"),
+	69: func(data byte) BlockState {
+		powered := boolString(data&8 != 0)
+		switch data & 7 {
+		case 0:
+			return blockState("lever", "face", "ceiling", "facing", "west", "powered", powered)
+		case 5:
+			return blockState("lever", "face", "floor", "facing", "north", "powered", powered)
+		case 6:
+			return blockState("lever", "face", "floor", "facing", "west", "powered", powered)
+		case 7:
+			return blockState("lever", "face", "ceiling", "facing", "north", "powered", powered)
+		default:
+			return blockState("lever", "face", "wall", "facing", facingEWSN[(data&7-1)&3], "powered", powered)
+		}
+	},
+	70: func(data byte) BlockState {
+		return blockState("stone_pressure_plate", "powered", boolString(data&1 != 0))
+	},
+	71: door("iron_door"),
+	72: func(data byte) BlockState {
+		return blockState("oak_pressure_plate", "powered", boolString(data&1 != 0))
+	},
+	73: simple("redstone_ore", "lit", "false"),
+	74: simple("redstone_ore", "lit", "true"),
+	75: torch("redstone_torch", "redstone_wall_torch", "lit", "false"),
+	76: torch("redstone_torch", "redstone_wall_torch", "lit", "true"),
+	77: button("stone_button"),
+	78: func(data byte) BlockState { return blockState("snow", "layers", intString(data&7+1)) },
+	79: simple("ice"),
+	80: simple("snow_block"),
+	81: aged("cactus", 15),
+	82: simple("clay"),
+	83: aged("sugar_cane", 15),
+	84: func(data byte) BlockState { return blockState("jukebox", "has_record", boolString(data&1 != 0)) },
+	85: fence("oak_fence"),
+	86: func(data byte) BlockState { return blockState("carved_pumpkin", "facing", facingSWNE[data&3]) },
+	87: simple("netherrack"),
+	88: simple("soul_sand"),
+	89: simple("glowstone"),
+	90: func(data byte) BlockState {
+		axis := "x"
+		if data == 2 {
+			axis = "z"
+		}
+		return blockState("nether_portal", "axis", axis)
+	},
+	91: func(data byte) BlockState { return blockState("jack_o_lantern", "facing", facingSWNE[data&3]) },
+	92: func(data byte) BlockState { return blockState("cake", "bites", intString(data&7)) },
+	93: diode("repeater", false),
+	94: diode("repeater", true),
+	95: colored("stained_glass"),
+	96: trapdoor("oak_trapdoor"),
+	97: byData(blockState("infested_stone"), blockState("infested_cobblestone"),
+		blockState("infested_stone_bricks"), blockState("infested_mossy_stone_bricks"),
+		blockState("infested_cracked_stone_bricks"), blockState("infested_chiseled_stone_bricks")),
+	98: byData(blockState("stone_bricks"), blockState("mossy_stone_bricks"), blockState("cracked_stone_bricks"),
+		blockState("chiseled_stone_bricks")),
+	99:  mushroomBlock("brown_mushroom_block"),
+	100: mushroomBlock("red_mushroom_block"),
+	101: fence("iron_bars"),
+	102: fence("glass_pane"),
+	103: simple("melon"),
+	104: aged("pumpkin_stem", 7),
+	105: aged("melon_stem", 7),
+	106: func(data byte) BlockState {
+		return blockState("vine", "south", boolString(data&1 != 0), "west", boolString(data&2 != 0),
+			"north", boolString(data&4 != 0), "east", boolString(data&8 != 0), "up", "false")
+	},
+	107: fenceGate("oak_fence_gate"),
+	108: stairs("brick_stairs"),
+	109: stairs("stone_brick_stairs"),
+	110: simple("mycelium", "snowy", "false"),
+	111: simple("lily_pad"),
+	112: simple("nether_bricks"),
+	113: fence("nether_brick_fence"),
+	114: stairs("nether_brick_stairs"),
+	115: aged("nether_wart", 3),
+	116: simple("enchanting_table"),
+	117: func(data byte) BlockState {
+		return blockState("brewing_stand", "has_bottle_0", boolString(data&1 != 0),
+			"has_bottle_1", boolString(data&2 != 0), "has_bottle_2", boolString(data&4 != 0))
+	},
+	118: func(data byte) BlockState { return blockState("cauldron", "level", intString(data&3)) },
+	119: simple("end_portal"),
+	120: func(data byte) BlockState {
+		return blockState("end_portal_frame", "eye", boolString(data&4 != 0), "facing", facingSWNE[data&3])
+	},
+	121: simple("end_stone"),
+	122: simple("dragon_egg"),
+	123: simple("redstone_lamp", "lit", "false"),
+	124: simple("redstone_lamp", "lit", "true"),
+	125: doubleSlab(woodenSlabs, nil),
+	126: slab(woodenSlabs),
+	127: func(data byte) BlockState {
+		return blockState("cocoa", "age", intString(data>>2&3), "facing", facingSWNE[data&3])
+	},
+	128: stairs("sandstone_stairs"),
+	129: simple("emerald_ore"),
+	130: horizontalFacing("ender_chest", "waterlogged", "false"),
+	131: func(data byte) BlockState {
+		return blockState("tripwire_hook", "facing", facingSWNE[data&3], "attached", boolString(data&4 != 0),
+			"powered", boolString(data&8 != 0))
+	},
+	132: func(data byte) BlockState {
+		return blockState("tripwire", "attached", boolString(data&4 != 0), "disarmed", boolString(data&8 != 0),
+			"powered", boolString(data&1 != 0), "east", "false", "north", "false", "south", "false",
+			"west", "false")
+	},
+	133: simple("emerald_block"),
+	134: stairs("spruce_stairs"),
+	135: stairs("birch_stairs"),
+	136: stairs("jungle_stairs"),
+	137: commandBlock("command_block"),
+	138: simple("beacon"),
+	139: func(data byte) BlockState {
+		name := "cobblestone_wall"
+		if data == 1 {
+			name = "mossy_cobblestone_wall"
+		}
+		return blockState(name, "east", "false", "north", "false", "south", "false", "up", "true",
+			"waterlogged", "false", "west", "false")
+	},
+	140: simple("flower_pot"),
+	141: aged("carrots", 7),
+	142: aged("potatoes", 7),
+	143: button("oak_button"),
+	144: func(data byte) BlockState {
+		if data&7 < 2 {
+			return blockState("skeleton_skull", "rotation", "0")
+		}
+		return blockState("skeleton_wall_skull", "facing", facing2to5(data&7))
+	},
+	145: func(data byte) BlockState {
+		names := [4]string{"anvil", "chipped_anvil", "damaged_anvil", "anvil"}
+		return blockState(names[data>>2&3], "facing", facingSWNE[data&3])
+	},
+	146: horizontalFacing("trapped_chest", "type", "single", "waterlogged", "false"),
+	147: func(data byte) BlockState {
+		return blockState("light_weighted_pressure_plate", "power", intString(data&15))
+	},
+	148: func(data byte) BlockState {
+		return blockState("heavy_weighted_pressure_plate", "power", intString(data&15))
+	},
+	149: comparator,
+	150: comparator,
+	151: func(data byte) BlockState {
+		return blockState("daylight_detector", "inverted", "false", "power", intString(data&15))
+	},
+	152: simple("redstone_block"),
+	153: simple("nether_quartz_ore"),
+	154: func(data byte) BlockState {
+		facing := facingDUNSWE[data&7]
+		if facing == "up" {
+			facing = "down"
+		}
+		return blockState("hopper", "enabled", boolString(data&8 == 0), "facing", facing)
+	},
+	155: byData(blockState("quartz_block"), blockState("chiseled_quartz_block"),
+		blockState("quartz_pillar", "axis", "y"), blockState("quartz_pillar", "axis", "x"),
+		blockState("quartz_pillar", "axis", "z")),
+	156: stairs("quartz_stairs"),
+	157: rail("activator_rail", true),
+	158: dispenser("dropper"),
+	159: colored("terracotta"),
+	160: func(data byte) BlockState {
+		return blockState(legacyColors[data&15]+"_stained_glass_pane", "east", "false", "north", "false",
+			"south", "false", "waterlogged", "false", "west", "false")
+	},
+	161: leaves(legacyWoods[4:]),
+	162: logBlock(legacyWoods[4:]),
+	163: stairs("acacia_stairs"),
+	164: stairs("dark_oak_stairs"),
+	165: simple("slime_block"),
+	166: simple("barrier"),
+	167: trapdoor("iron_trapdoor"),
+	168: byData(blockState("prismarine"), blockState("prismarine_bricks"), blockState("dark_prismarine")),
+	169: simple("sea_lantern"),
+	170: axisBlock("hay_block"),
+	171: colored("carpet"),
+	172: simple("terracotta"),
+	173: simple("coal_block"),
+	174: simple("packed_ice"),
+	175: func(data byte) BlockState {
+		if data&8 != 0 {
+			// The type of plant is stored in the lower half only.
+			return blockState("sunflower", "half", "upper")
+		}
+		names := [8]string{"sunflower", "lilac", "tall_grass", "large_fern", "rose_bush", "peony", "sunflower",
+			"sunflower"}
+		return blockState(names[data&7], "half", "lower")
+	},
+	176: func(data byte) BlockState { return blockState("white_banner", "rotation", intString(data&15)) },
+	177: horizontalFacing("white_wall_banner"),
+	178: func(data byte) BlockState {
+		return blockState("daylight_detector", "inverted", "true", "power", intString(data&15))
+	},
+	179: byData(blockState("red_sandstone"), blockState("chiseled_red_sandstone"), blockState("cut_red_sandstone")),
+	180: stairs("red_sandstone_stairs"),
+	181: doubleSlab(redSandstoneSlabs, map[byte]string{8: "smooth_red_sandstone"}),
+	182: slab(redSandstoneSlabs),
+	183: fenceGate("spruce_fence_gate"),
+	184: fenceGate("birch_fence_gate"),
+	185: fenceGate("jungle_fence_gate"),
+	186: fenceGate("dark_oak_fence_gate"),
+	187: fenceGate("acacia_fence_gate"),
+	188: fence("spruce_fence"),
+	189: fence("birch_fence"),
+	190: fence("jungle_fence"),
+	191: fence("dark_oak_fence"),
+	192: fence("acacia_fence"),
+	193: door("spruce_door"),
+	194: door("birch_door"),
+	195: door("jungle_door"),
+	196: door("acacia_door"),
+	197: door("dark_oak_door"),
+	198: func(data byte) BlockState { return blockState("end_rod", "facing", facingDUNSWE[data&7]) },
+	199: simple("chorus_plant", "down", "false", "east", "false", "north", "false", "south", "false", "up", "false",
+		"west", "false"),
+	200: aged("chorus_flower", 5),
+	201: simple("purpur_block"),
+	202: axisBlock("purpur_pillar"),
+	203: stairs("purpur_stairs"),
+	204: doubleSlab(purpurSlabs, nil),
+	205: slab(purpurSlabs),
+	206: simple("end_stone_bricks"),
+	207: aged("beetroots", 3),
+	208: simple("grass_path"),
+	209: simple("end_gateway"),
+	210: commandBlock("repeating_command_block"),
+	211: commandBlock("chain_command_block"),
+	212: aged("frosted_ice", 3),
+	213: simple("magma_block"),
+	214: simple("nether_wart_block"),
+	215: simple("red_nether_bricks"),
+	216: axisBlock("bone_block"),
+	217: simple("structure_void"),
+	218: func(data byte) BlockState {
+		return blockState("observer", "facing", facingDUNSWE[data&7], "powered", boolString(data&8 != 0))
+	},
+	251: colored("concrete"),
+	252: colored("concrete_powder"),
+	255: byData(blockState("structure_block", "mode", "save"), blockState("structure_block", "mode", "load"),
+		blockState("structure_block", "mode", "corner"), blockState("structure_block", "mode", "data")),
+}
+
+func init() {
+	for i, color := range legacyColors {
+		legacyBlocks[byte(219+i)] = shulkerBox(color)
+		legacyBlocks[byte(235+i)] = glazedTerracotta(color)
+	}
+}
+
+// flattenLegacyBlock returns the block state for a legacy block id and data value, or air for ids that 1.12 did not
+// have.
+func flattenLegacyBlock(id, data byte) BlockState {
+	if convert, ok := legacyBlocks[id]; ok {
+		return convert(data & 15)
+	}
+	return blockState("air")
+}
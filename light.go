@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LightMode decides what happens to the block and sky light of each section when a world is written.
+type LightMode int
+
+const (
+	// LightKeep writes light as the source world has it, even for chunks whose LightPopulated tag says the game never
+	// finished lighting them.
+	LightKeep LightMode = iota
+	// LightStrip leaves out the light of every section, for loaders that relight chunks anyway.
+	LightStrip
+	// LightStripUnpopulated leaves out light only for chunks whose LightPopulated tag is 0.
+	LightStripUnpopulated
+)
+
+var lightModeNames = map[string]LightMode{
+	"keep":              LightKeep,
+	"strip":             LightStrip,
+	"strip-unpopulated": LightStripUnpopulated,
+}
+
+func ParseLightMode(name string) (LightMode, error) {
+	if mode, ok := lightModeNames[strings.ToLower(name)]; ok {
+		return mode, nil
+	}
+	return LightKeep, fmt.Errorf("unknown light mode %q (expected keep, strip or strip-unpopulated)", name)
+}
+
+// strips reports whether the mode leaves out the light of the given chunk.
+func (m LightMode) strips(chunk MinecraftChunk) bool {
+	switch m {
+	case LightStrip:
+		return true
+	case LightStripUnpopulated:
+		return chunk.LightPopulated == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import "sort"
+
+// Light in the legacy format is calculated from two properties of each block id: how much light it lets through and
+// how much light it gives off. Data values are ignored, since no block from before 1.13 changes either depending on
+// its data value.
+
+// legacyLightOpacity is how much each block id reduces light passing through it. Blocks are opaque unless listed in
+// legacyTranslucentBlocks.
+var legacyLightOpacity [256]byte
+
+// legacyTranslucentBlocks lists the blocks that let light through, mapped to how much they reduce it by. Most are not
+// full cubes, such as torches, fences and plants.
+var legacyTranslucentBlocks = map[byte]byte{
+	0: 0, 6: 0, 8: 3, 9: 3, 10: 0, 11: 0, 18: 1, 20: 0, 26: 0, 27: 0, 28: 0, 29: 0, 30: 1, 31: 0, 32: 0, 33: 0, 34: 0,
+	36: 0, 37: 0, 38: 0, 39: 0, 40: 0, 50: 0, 51: 0, 52: 0, 54: 0, 55: 0, 59: 0, 63: 0, 64: 0, 65: 0, 66: 0, 68: 0,
+	69: 0, 70: 0, 71: 0, 72: 0, 75: 0, 76: 0, 77: 0, 78: 0, 79: 3, 81: 0, 83: 0, 85: 0, 90: 0, 92: 0, 93: 0, 94: 0,
+	95: 0, 96: 0, 101: 0, 102: 0, 104: 0, 105: 0, 106: 0, 107: 0, 111: 0, 113: 0, 115: 0, 116: 0, 117: 0, 118: 0,
+	119: 0, 120: 0, 122: 0, 127: 0, 130: 0, 131: 0, 132: 0, 138: 0, 139: 0, 140: 0, 141: 0, 142: 0, 143: 0, 144: 0,
+	145: 0, 146: 0, 147: 0, 148: 0, 149: 0, 150: 0, 151: 0, 154: 0, 157: 0, 160: 0, 161: 1, 166: 0, 167: 0, 171: 0,
+	175: 0, 176: 0, 177: 0, 178: 0, 183: 0, 184: 0, 185: 0, 186: 0, 187: 0, 188: 0, 189: 0, 190: 0, 191: 0, 192: 0,
+	193: 0, 194: 0, 195: 0, 196: 0, 197: 0, 198: 0, 199: 0, 200: 0, 207: 0, 212: 3, 217: 0, 219: 0, 220: 0, 221: 0,
+	222: 0, 223: 0, 224: 0, 225: 0, 226: 0, 227: 0, 228: 0, 229: 0, 230: 0, 231: 0, 232: 0, 233: 0, 234: 0,
+}
+
+// legacyLightEmission is how much light each block id gives off.
+var legacyLightEmission = [256]byte{
+	10: 15, 11: 15, 39: 1, 50: 14, 51: 15, 62: 13, 74: 9, 76: 7, 89: 15, 90: 11, 91: 15, 94: 9, 117: 1, 119: 15,
+	122: 1, 124: 15, 130: 7, 138: 15, 150: 9, 169: 15, 198: 14, 213: 3,
+}
+
+func init() {
+	for id := range legacyLightOpacity {
+		legacyLightOpacity[id] = 15
+	}
+	for id, opacity := range legacyTranslucentBlocks {
+		legacyLightOpacity[id] = opacity
+	}
+}
+
+const chunkVolume = 16 * 16 * 256
+
+// chunkLight holds the blocks and light of a whole chunk column, indexed like a section but with the full height:
+// y<<8 | z<<4 | x. Missing sections are air.
+type chunkLight struct {
+	blocks     [chunkVolume]byte
+	skyLight   [chunkVolume]byte
+	blockLight [chunkVolume]byte
+	heightMap  [256]int
+	neighbors  [4]*chunkLight // west, east, north, south
+}
+
+func newChunkLight(chunk MinecraftChunk) *chunkLight {
+	light := &chunkLight{}
+	for _, section := range chunk.Sections {
+		if int(section.Y) < 16 && len(section.Blocks) == 4096 {
+			copy(light.blocks[int(section.Y)<<12:], section.Blocks)
+		}
+	}
+	for column := range light.heightMap {
+		height := 256
+		for height > 0 && legacyLightOpacity[light.blocks[(height-1)<<8|column]] == 0 {
+			height--
+		}
+		light.heightMap[column] = height
+	}
+	return light
+}
+
+// computeHeightMap returns, for every column of the chunk, the height of the lowest block that receives the full
+// light of the sky.
+func computeHeightMap(chunk MinecraftChunk) []int {
+	return append([]int(nil), newChunkLight(chunk).heightMap[:]...)
+}
+
+// RecomputeHeightMaps rebuilds the height map of every chunk from its blocks.
+func (world *AnvilWorld) RecomputeHeightMaps() {
+	for coord, chunk := range world.chunks {
+		chunk.HeightMap = computeHeightMap(chunk)
+		world.chunks[coord] = chunk
+	}
+}
+
+// RecomputeLight rebuilds the height maps and the sky and block light of every chunk from its blocks. Light spreads
+// across chunk borders, but not into chunks that are not part of the world, and the world is assumed to have a sky.
+//
+// Light travels at most 14 blocks, so the light of a row of chunks is final once the rows on either side of it are
+// lit. The world is lit a row at a time along Z, keeping only the rows around the current one in memory.
+func (world *AnvilWorld) RecomputeLight() {
+	rows := make(map[int][]ChunkCoord)
+	for coord := range world.chunks {
+		rows[coord.Z] = append(rows[coord.Z], coord)
+	}
+	rowZs := make([]int, 0, len(rows))
+	for z := range rows {
+		rowZs = append(rowZs, z)
+	}
+	sort.Ints(rowZs)
+
+	window := &lightWindow{world: world, volumes: make(map[ChunkCoord]*chunkLight)}
+	for i, z := range rowZs {
+		window.load(rows[z])
+		window.load(rows[z+1])
+		window.sky.spread(func(light *chunkLight) *[chunkVolume]byte { return &light.skyLight })
+		window.block.spread(func(light *chunkLight) *[chunkVolume]byte { return &light.blockLight })
+		window.store(rows[z])
+		if i > 0 {
+			// Rows further back were unloaded in earlier steps.
+			window.unload(rows[rowZs[i-1]])
+		}
+	}
+}
+
+// lightWindow holds the chunks of the rows that are being lit.
+type lightWindow struct {
+	world      *AnvilWorld
+	volumes    map[ChunkCoord]*chunkLight
+	sky, block lightQueue
+}
+
+// neighborOffsets are the chunks next to a chunk in the order of chunkLight.neighbors.
+var neighborOffsets = [4]ChunkCoord{{X: -1}, {X: 1}, {Z: -1}, {Z: 1}}
+
+// load adds the chunks that are not loaded yet to the window and queues the light they give off. Light already in
+// the chunks next to them is queued again, so that it spreads into them.
+func (w *lightWindow) load(coords []ChunkCoord) {
+	var added []*chunkLight
+	for _, coord := range coords {
+		if _, ok := w.volumes[coord]; !ok {
+			w.volumes[coord] = newChunkLight(w.world.chunks[coord])
+			added = append(added, w.volumes[coord])
+		}
+	}
+	for _, coord := range coords {
+		light := w.volumes[coord]
+		for side, offset := range neighborOffsets {
+			neighbor := w.volumes[ChunkCoord{X: coord.X + offset.X, Z: coord.Z + offset.Z}]
+			if neighbor == nil || light.neighbors[side] == neighbor {
+				continue
+			}
+			light.neighbors[side] = neighbor
+			// Sides are paired up, so side^1 is the opposite one.
+			neighbor.neighbors[side^1] = light
+			w.requeueSide(neighbor, side^1)
+		}
+	}
+	for _, light := range added {
+		w.queueSources(light)
+	}
+}
+
+// queueSources queues the light a chunk gives off: the sky above its blocks and its glowing blocks.
+func (w *lightWindow) queueSources(light *chunkLight) {
+	for column, height := range light.heightMap {
+		// Open sky only needs to spread from where it is next to a taller column or above the top block.
+		spreadTo := height + 1
+		if around := light.highestColumnAround(column); around > spreadTo {
+			spreadTo = around
+		}
+		for y := height; y < 256; y++ {
+			if y < spreadTo {
+				w.sky.add(light, &light.skyLight, y<<8|column, 15)
+			} else {
+				light.skyLight[y<<8|column] = 15
+			}
+		}
+	}
+	for i, id := range light.blocks {
+		if emission := legacyLightEmission[id]; emission > 0 {
+			w.block.add(light, &light.blockLight, i, emission)
+		}
+	}
+}
+
+// requeueSide queues the lit blocks along one side of a chunk again, after a chunk was loaded next to that side.
+func (w *lightWindow) requeueSide(light *chunkLight, side int) {
+	for y := 0; y < 256; y++ {
+		for i := 0; i < 16; i++ {
+			var x, z int
+			switch side {
+			case 0:
+				x, z = 0, i
+			case 1:
+				x, z = 15, i
+			case 2:
+				x, z = i, 0
+			case 3:
+				x, z = i, 15
+			}
+			index := y<<8 | z<<4 | x
+			w.sky.requeue(light, index, light.skyLight[index])
+			w.block.requeue(light, index, light.blockLight[index])
+		}
+	}
+}
+
+// store writes the light of the chunks, which must be final, back to the world.
+func (w *lightWindow) store(coords []ChunkCoord) {
+	for _, coord := range coords {
+		light := w.volumes[coord]
+		chunk := w.world.chunks[coord]
+		chunk.HeightMap = append([]int(nil), light.heightMap[:]...)
+		for i := range chunk.Sections {
+			section := &chunk.Sections[i]
+			section.SkyLight = packNibbles(light.skyLight[int(section.Y)<<12:][:4096])
+			section.BlockLight = packNibbles(light.blockLight[int(section.Y)<<12:][:4096])
+		}
+		chunk.LightPopulated = 1
+		w.world.chunks[coord] = chunk
+	}
+}
+
+// unload removes chunks whose light was stored and which no chunk left to light needs any more.
+func (w *lightWindow) unload(coords []ChunkCoord) {
+	for _, coord := range coords {
+		light, ok := w.volumes[coord]
+		if !ok {
+			continue
+		}
+		for side, neighbor := range light.neighbors {
+			if neighbor != nil {
+				neighbor.neighbors[side^1] = nil
+			}
+		}
+		delete(w.volumes, coord)
+	}
+}
+
+// highestColumnAround returns the height of the tallest of the four columns next to the given one.
+func (light *chunkLight) highestColumnAround(column int) int {
+	x, z := column&15, column>>4
+	highest := 0
+	for _, offset := range [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		chunk, nx, nz := light.column(x+offset[0], z+offset[1])
+		if chunk != nil && chunk.heightMap[nz<<4|nx] > highest {
+			highest = chunk.heightMap[nz<<4|nx]
+		}
+	}
+	return highest
+}
+
+// column finds a column that may be one block outside of this chunk, returning nil if it is in a chunk that is not
+// part of the world.
+func (light *chunkLight) column(x, z int) (*chunkLight, int, int) {
+	switch {
+	case x < 0:
+		return light.neighbors[0], 15, z
+	case x > 15:
+		return light.neighbors[1], 0, z
+	case z < 0:
+		return light.neighbors[2], x, 15
+	case z > 15:
+		return light.neighbors[3], x, 0
+	default:
+		return light, x, z
+	}
+}
+
+type lightNode struct {
+	chunk *chunkLight
+	index int
+}
+
+// lightQueue spreads light from the brightest blocks outward, so that each block is only visited once per level.
+type lightQueue struct {
+	levels [16][]lightNode
+}
+
+// requeue queues a block again at the light level it already has, so that its light spreads to blocks that were not
+// there when it was first queued.
+func (q *lightQueue) requeue(chunk *chunkLight, index int, level byte) {
+	if level > 1 {
+		q.levels[level] = append(q.levels[level], lightNode{chunk: chunk, index: index})
+	}
+}
+
+func (q *lightQueue) add(chunk *chunkLight, light *[chunkVolume]byte, index int, level byte) {
+	if light[index] >= level {
+		return
+	}
+	light[index] = level
+	// Light of level 1 does not spread any further.
+	if level > 1 {
+		q.levels[level] = append(q.levels[level], lightNode{chunk: chunk, index: index})
+	}
+}
+
+func (q *lightQueue) spread(lightOf func(light *chunkLight) *[chunkVolume]byte) {
+	for level := 15; level > 1; level-- {
+		for i := 0; i < len(q.levels[level]); i++ {
+			node := q.levels[level][i]
+			if lightOf(node.chunk)[node.index] != byte(level) {
+				// A brighter path reached this block after it was queued.
+				continue
+			}
+			x, y, z := node.index&15, node.index>>8, (node.index>>4)&15
+			for _, neighbor := range [6]struct {
+				dx, dy, dz int
+			}{{-1, 0, 0}, {1, 0, 0}, {0, -1, 0}, {0, 1, 0}, {0, 0, -1}, {0, 0, 1}} {
+				ny := y + neighbor.dy
+				if ny < 0 || ny > 255 {
+					continue
+				}
+				chunk, nx, nz := node.chunk.column(x+neighbor.dx, z+neighbor.dz)
+				if chunk == nil {
+					continue
+				}
+				index := ny<<8 | nz<<4 | nx
+				reduction := int(legacyLightOpacity[chunk.blocks[index]])
+				if reduction < 1 {
+					reduction = 1
+				}
+				if next := level - reduction; next > 0 {
+					q.add(chunk, lightOf(chunk), index, byte(next))
+				}
+			}
+		}
+		q.levels[level] = nil
+	}
+}
+
+func packNibbles(values []byte) []byte {
+	nibbles := make([]byte, len(values)/2)
+	for i, value := range values {
+		setNibble(nibbles, i, value)
+	}
+	return nibbles
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestRecomputeLight(t *testing.T) {
+	// Chunk 0,0 has a stone roof at y=10 with a glowstone block below it, next to the chunk border. Chunk 1,0 only has
+	// a stone floor, so the sky shines in under the roof from the side.
+	roofed := MinecraftChunkSection{Y: 0, Blocks: make([]byte, 4096), Data: make([]byte, 2048)}
+	open := MinecraftChunkSection{Y: 0, Blocks: make([]byte, 4096), Data: make([]byte, 2048)}
+	for column := 0; column < 256; column++ {
+		roofed.Blocks[10<<8|column] = 1
+		open.Blocks[column] = 1
+	}
+	roofed.Blocks[5<<8|2<<4|15] = 89
+
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{
+		{X: 0, Z: 0}: {X: 0, Z: 0, Sections: []MinecraftChunkSection{roofed}},
+		{X: 1, Z: 0}: {X: 1, Z: 0, Sections: []MinecraftChunkSection{open}},
+	}}
+	world.RecomputeLight()
+
+	roofedChunk, openChunk := world.chunks[ChunkCoord{X: 0, Z: 0}], world.chunks[ChunkCoord{X: 1, Z: 0}]
+	if roofedChunk.HeightMap[0] != 11 || openChunk.HeightMap[0] != 1 {
+		t.Errorf("expected heights 11 and 1, got %d and %d", roofedChunk.HeightMap[0], openChunk.HeightMap[0])
+	}
+	if roofedChunk.LightPopulated != 1 {
+		t.Error("chunk is not marked as lit")
+	}
+
+	for _, test := range []struct {
+		name     string
+		light    []byte
+		x, y, z  int
+		expected byte
+	}{
+		{"sky above the roof", roofedChunk.Sections[0].SkyLight, 3, 11, 3, 15},
+		{"sky under the roof at the border", roofedChunk.Sections[0].SkyLight, 15, 3, 8, 14},
+		{"sky further under the roof", roofedChunk.Sections[0].SkyLight, 12, 3, 8, 11},
+		{"sky in the roof", roofedChunk.Sections[0].SkyLight, 3, 10, 3, 0},
+		{"glowstone", roofedChunk.Sections[0].BlockLight, 15, 5, 2, 15},
+		{"glowstone next to it", roofedChunk.Sections[0].BlockLight, 14, 5, 2, 14},
+		{"glowstone across the border", openChunk.Sections[0].BlockLight, 0, 5, 2, 14},
+		{"glowstone further across the border", openChunk.Sections[0].BlockLight, 2, 4, 2, 11},
+	} {
+		if light := getNibble(test.light, test.y<<8|test.z<<4|test.x); light != test.expected {
+			t.Errorf("%s: expected light %d, got %d", test.name, test.expected, light)
+		}
+	}
+}
+
+func TestRecomputeLight_rows(t *testing.T) {
+	// Chunks are lit a row at a time, so light has to cross from each row into the one before and after it. Chunk 0,0
+	// has a stone roof at y=10, chunk 0,1 only a floor, and each has a glowstone block at their shared border.
+	roofed := MinecraftChunkSection{Y: 0, Blocks: make([]byte, 4096), Data: make([]byte, 2048)}
+	open := MinecraftChunkSection{Y: 0, Blocks: make([]byte, 4096), Data: make([]byte, 2048)}
+	for column := 0; column < 256; column++ {
+		roofed.Blocks[10<<8|column] = 1
+		open.Blocks[column] = 1
+	}
+	roofed.Blocks[5<<8|15<<4|2] = 89
+	open.Blocks[5<<8|0<<4|12] = 89
+
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{
+		{X: 0, Z: 0}: {X: 0, Z: 0, Sections: []MinecraftChunkSection{roofed}},
+		{X: 0, Z: 1}: {X: 0, Z: 1, Sections: []MinecraftChunkSection{open}},
+		{X: 0, Z: 3}: {X: 0, Z: 3, Sections: []MinecraftChunkSection{{Y: 0, Blocks: make([]byte, 4096),
+			Data: make([]byte, 2048)}}},
+	}}
+	world.RecomputeLight()
+
+	roofedLight := world.chunks[ChunkCoord{X: 0, Z: 0}].Sections[0]
+	openLight := world.chunks[ChunkCoord{X: 0, Z: 1}].Sections[0]
+	for _, test := range []struct {
+		name     string
+		light    []byte
+		x, y, z  int
+		expected byte
+	}{
+		{"sky under the roof at the border", roofedLight.SkyLight, 8, 3, 15, 14},
+		{"glowstone across the border into the next row", openLight.BlockLight, 2, 5, 0, 14},
+		{"glowstone across the border into the previous row", roofedLight.BlockLight, 12, 5, 15, 14},
+		{"sky in a row after a gap", world.chunks[ChunkCoord{X: 0, Z: 3}].Sections[0].SkyLight, 5, 0, 5, 15},
+	} {
+		if light := getNibble(test.light, test.y<<8|test.z<<4|test.x); light != test.expected {
+			t.Errorf("%s: expected light %d, got %d", test.name, test.expected, light)
+		}
+	}
+}
+
+func TestWriteAsSlime_missingHeightMap(t *testing.T) {
+	world := newTestWorld()
+	for coord, chunk := range world.chunks {
+		chunk.HeightMap = nil
+		world.chunks[coord] = chunk
+	}
+	read, err := ReadSlimeWorld(context.Background(), bytes.NewReader(writeTestSlime(t, world)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for coord, chunk := range read.chunks {
+		if chunk.HeightMap[0] != 1 {
+			t.Errorf("chunk %v: expected a height of 1, got %d", coord, chunk.HeightMap[0])
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+type LogFormat int
+
+const (
+	LogFormatText LogFormat = iota
+	LogFormatJSON
+)
+
+func ParseLogFormat(format string) (LogFormat, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return LogFormatText, nil
+	case "json":
+		return LogFormatJSON, nil
+	default:
+		return LogFormatText, fmt.Errorf("unknown log format %q (expected text or json)", format)
+	}
+}
+
+// Logger writes leveled, structured log lines. Each call takes a message followed by alternating keys and values,
+// i.e. logger.Info("region loaded", "region", name, "chunks", 12). It is safe for concurrent use.
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	level  LogLevel
+	format LogFormat
+}
+
+func NewLogger(out io.Writer, level LogLevel, format LogFormat) *Logger {
+	return &Logger{out: out, level: level, format: format}
+}
+
+// logger is the logger used by the command line tool. main replaces it once the logging flags have been parsed.
+var logger = NewLogger(os.Stderr, LogLevelInfo, LogFormatText)
+
+func (l *Logger) Enabled(level LogLevel) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LogLevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LogLevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LogLevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LogLevelError, msg, keyvals)
+}
+
+func (l *Logger) log(level LogLevel, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "(missing)")
+	}
+
+	var line bytes.Buffer
+	if l.format == LogFormatJSON {
+		l.formatJSON(&line, level, msg, keyvals)
+	} else {
+		l.formatText(&line, level, msg, keyvals)
+	}
+	line.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = line.WriteTo(l.out)
+}
+
+func (l *Logger) formatText(line *bytes.Buffer, level LogLevel, msg string, keyvals []interface{}) {
+	line.WriteString(strings.ToUpper(level.String()))
+	line.WriteByte(' ')
+	line.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		value := fmt.Sprint(logValue(keyvals[i+1]))
+		if strings.ContainsAny(value, " \t\n\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		_, _ = fmt.Fprintf(line, " %v=%s", keyvals[i], value)
+	}
+}
+
+func (l *Logger) formatJSON(line *bytes.Buffer, level LogLevel, msg string, keyvals []interface{}) {
+	// Fields are written by hand rather than through a map so that they keep the order they were logged in.
+	writeField := func(key string, value interface{}) {
+		encodedKey, _ := json.Marshal(key)
+		encodedValue, err := json.Marshal(value)
+		if err != nil {
+			encodedValue, _ = json.Marshal(fmt.Sprint(value))
+		}
+		line.Write(encodedKey)
+		line.WriteByte(':')
+		line.Write(encodedValue)
+	}
+
+	line.WriteByte('{')
+	writeField("time", time.Now().UTC().Format(time.RFC3339Nano))
+	line.WriteByte(',')
+	writeField("level", level.String())
+	line.WriteByte(',')
+	writeField("msg", msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		line.WriteByte(',')
+		writeField(fmt.Sprint(keyvals[i]), logValue(keyvals[i+1]))
+	}
+	line.WriteByte('}')
+}
+
+// logValue converts values that do not format usefully on their own.
+func logValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	default:
+		return v
+	}
+}
@@ -1,14 +1,25 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/klauspost/compress/zstd"
 	"github.com/urfave/cli/v2"
-	"log"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
+// exitInterrupted is the conventional exit status for a process stopped by SIGINT.
+const exitInterrupted = 130
+
 func main() {
 	app := &cli.App{
 		Name:  "anvil2slime",
@@ -19,46 +30,606 @@ func main() {
 				Aliases: []string{"o"},
 				Usage:   "writes the Slime region to the specified `FILE`",
 			},
+			&cli.BoolFlag{
+				Name:  "no-clobber",
+				Usage: "refuse to overwrite an existing output file",
+			},
+			&cli.StringSliceFlag{
+				Name:  "transform",
+				Usage: "rotates or mirrors the world around 0,0 with `TRANSFORM` (rotate90, rotate180, rotate270, mirror-x or mirror-z); may be repeated",
+			},
+			&cli.StringFlag{
+				Name:  "offset",
+				Usage: "moves the world by `DX,DZ` chunks, along with its entities and tile entities",
+			},
+			&cli.BoolFlag{
+				Name:  "recenter",
+				Usage: "moves the world toward 0,0 if its chunk coordinates do not fit in a Slime file",
+			},
+			&cli.BoolFlag{
+				Name:  "clip-height",
+				Usage: "drops the sections of 1.18 and later worlds below y 0 or above y 255, which a Slime world cannot hold, instead of failing their region",
+			},
+			&cli.BoolFlag{
+				Name:  "recompute-heightmaps",
+				Usage: "rebuilds every height map from the blocks in the world",
+			},
+			&cli.BoolFlag{
+				Name:  "recompute-light",
+				Usage: "rebuilds every height map and recalculates sky and block light from the blocks in the world",
+			},
+			&cli.BoolFlag{
+				Name:  "flatten",
+				Usage: "converts legacy block ids to 1.13 block states, which needs Slime version 4 or later",
+			},
+			&cli.StringFlag{
+				Name:  "replace-blocks",
+				Usage: "replaces blocks according to the rules in `FILE`, one FROM -> TO rule per line",
+			},
+			&cli.StringFlag{
+				Name:  "biome",
+				Usage: "sets every column of the world to biome `NAME`, given as a name such as plains or a numeric id",
+			},
+			&cli.StringFlag{
+				Name:  "remap-biomes",
+				Usage: "replaces biomes according to the mappings in `FILE`, one FROM -> TO mapping per line",
+			},
+			&cli.Int64Flag{
+				Name:  "min-inhabited-time",
+				Usage: "drops chunks that players spent fewer than `TICKS` near, according to their InhabitedTime (20 ticks are a second)",
+			},
+			&cli.BoolFlag{
+				Name:  "drop-unfinished-chunks",
+				Usage: "drops chunks the game never finished generating: those whose Status is not full, or whose TerrainPopulated or LightPopulated tag is 0",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include-entity",
+				Usage: "keeps only entities whose id matches `PATTERN`, i.e. minecraft:armor_stand; may be repeated",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude-entity",
+				Usage: "removes entities whose id matches `PATTERN`, i.e. minecraft:item; may be repeated",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include-tile-entity",
+				Usage: "keeps only tile entities whose id matches `PATTERN`; may be repeated",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude-tile-entity",
+				Usage: "removes tile entities whose id matches `PATTERN`, i.e. *:command_block; may be repeated",
+			},
+			&cli.IntFlag{
+				Name:  "slime-version",
+				Value: slimeDefaultVersion,
+				Usage: "writes Slime format `VERSION` (3 to 5); version 5 can leave light out entirely, and worlds with 1.13 block states use it unless told otherwise",
+			},
+			&cli.StringFlag{
+				Name:  "light",
+				Value: "keep",
+				Usage: "writes light according to `MODE`: keep, strip (for loaders that relight chunks) or strip-unpopulated (only for chunks the game never lit)",
+			},
+			&cli.BoolFlag{
+				Name:  "verify",
+				Usage: "reads every Slime file back after writing it and fails, without saving it, unless it matches the world that was written",
+			},
+			&cli.StringFlag{
+				Name:  "compression-level",
+				Value: "default",
+				Usage: "compresses the Slime world at `LEVEL` (fastest, default, better or best)",
+			},
+			&cli.StringFlag{
+				Name:  "compression-window",
+				Usage: "uses a zstd window of `SIZE` bytes, a power of two with an optional KiB or MiB suffix (default: depends on the level)",
+			},
+			&cli.IntFlag{
+				Name:  "compression-concurrency",
+				Value: 1,
+				Usage: "lets the compressor use `N` goroutines",
+			},
+			&cli.BoolFlag{
+				Name:  "benchmark-compression",
+				Usage: "reports the size and time of writing the world at each compression level instead of saving it",
+			},
+			&cli.StringFlag{
+				Name:  "log-format",
+				Value: "text",
+				Usage: "writes logs as `FORMAT` (text or json)",
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "only log errors",
+			},
+			&cli.BoolFlag{
+				Name:  "verbose",
+				Usage: "log debugging information",
+			},
 		},
+		Commands: []*cli.Command{
+			mergeCommand,
+			splitCommand,
+			renderCommand,
+			statsCommand,
+			diffCommand,
+			findCommand,
+		},
+		Before: configureLogging,
 		Action: func(c *cli.Context) error {
 			if c.NArg() == 0 {
-				_, _ = fmt.Fprintf(os.Stderr, "need a world to work with!\n")
+				logger.Error("need a world to work with!")
 				return nil
-			} else {
-				return processAnvilWorld(c.Args().Get(0), c.String("output"))
 			}
+
+			options, err := conversionOptionsFromFlags(c)
+			if err != nil {
+				return err
+			}
+			if c.Bool("benchmark-compression") {
+				return benchmarkAnvilWorld(c.Context, c.Args().Get(0), options)
+			}
+			return processAnvilWorld(c.Context, c.Args().Get(0), options)
 		},
 	}
 
+	exitOnRepeatedInterrupt()
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		if errors.Is(err, context.Canceled) {
+			logger.Error("interrupted")
+			os.Exit(exitInterrupted)
+		}
+		var withStatus exitStatusError
+		if errors.As(err, &withStatus) {
+			if withStatus.err != nil {
+				logger.Error(withStatus.err.Error())
+			}
+			os.Exit(withStatus.status)
+		}
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 }
 
-func processAnvilWorld(path string, saveTo string) (err error) {
-	startAnvilLoad := time.Now()
-	world, err := OpenAnvilWorld(filepath.Join(path, "region"))
+// exitStatusError makes the process exit with a status other than 1, for commands whose status means more than
+// success or failure. The error it wraps, if any, is logged first.
+type exitStatusError struct {
+	status int
+	err    error
+}
+
+func (e exitStatusError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.status)
+	}
+	return e.err.Error()
+}
+
+func (e exitStatusError) Unwrap() error {
+	return e.err
+}
+
+func configureLogging(c *cli.Context) error {
+	format, err := ParseLogFormat(c.String("log-format"))
 	if err != nil {
 		return err
 	}
+
+	level := LogLevelInfo
+	switch {
+	case c.Bool("quiet") && c.Bool("verbose"):
+		return errors.New("--quiet and --verbose cannot be used together")
+	case c.Bool("quiet"):
+		level = LogLevelError
+	case c.Bool("verbose"):
+		level = LogLevelDebug
+	}
+	logger = NewLogger(os.Stderr, level, format)
+	return nil
+}
+
+// newProgressReporter draws a progress bar when a person is watching and logs progress otherwise.
+func newProgressReporter() ProgressReporter {
+	if logger.format == LogFormatText && logger.level == LogLevelInfo && isTerminal(os.Stderr) {
+		return newTTYProgressBar(os.Stderr)
+	}
+	return newLogProgressReporter(logger)
+}
+
+// conversionOptions holds everything the command line can change about how a world is converted.
+type conversionOptions struct {
+	Output    string
+	NoClobber bool
+	Recenter  bool
+	// Orientations are applied in order, before the world is moved by Offset.
+	Orientations []Orientation
+	// Offset is how far to move the world, in chunks.
+	Offset ChunkCoord
+	// ChunkFilter drops chunks before anything else looks at them.
+	ChunkFilter ChunkFilter
+	// EntityFilter and TileEntityFilter remove entities and tile entities by id once the world has been moved.
+	EntityFilter     IDFilter
+	TileEntityFilter IDFilter
+	// RecomputeHeightMaps and RecomputeLight rebuild height maps, and light as well, once the rules for legacy blocks
+	// in Replacements have been applied.
+	RecomputeHeightMaps bool
+	RecomputeLight      bool
+	// Flatten converts legacy blocks to block states after light and height maps are recomputed, as the steps before
+	// it only understand legacy blocks.
+	Flatten bool
+	// Replacements apply to legacy blocks before light and height maps are recomputed, and to block states once the
+	// world has them, so that each rule sees the blocks it is written for.
+	Replacements []ReplacementRule
+	// Biomes replaces biomes after everything else, although no other step looks at them.
+	Biomes BiomeRemap
+	Slime  SlimeWriteOptions
+	// Verify reads each Slime file back before it is moved into place and compares it with the world.
+	Verify bool
+	// Anvil controls how Anvil worlds are read.
+	Anvil AnvilReadOptions
+}
+
+func conversionOptionsFromFlags(c *cli.Context) (options conversionOptions, err error) {
+	options.Output = c.String("output")
+	options.NoClobber = c.Bool("no-clobber")
+	options.Recenter = c.Bool("recenter")
+	options.RecomputeHeightMaps = c.Bool("recompute-heightmaps")
+	options.RecomputeLight = c.Bool("recompute-light")
+	options.Verify = c.Bool("verify")
+	options.Anvil = anvilReadOptionsFromFlags(c)
+	for _, name := range c.StringSlice("transform") {
+		orientation, err := ParseOrientation(name)
+		if err != nil {
+			return options, err
+		}
+		options.Orientations = append(options.Orientations, orientation)
+	}
+	if offset := c.String("offset"); offset != "" {
+		if options.Offset, err = parseChunkCoord(offset); err != nil {
+			return options, fmt.Errorf("invalid offset: %w", err)
+		}
+	}
+	options.ChunkFilter = ChunkFilter{
+		MinInhabitedTime: c.Int64("min-inhabited-time"),
+		RequireFull:      c.Bool("drop-unfinished-chunks"),
+	}
+	if options.ChunkFilter.MinInhabitedTime < 0 {
+		return options, errors.New("the minimum inhabited time cannot be negative")
+	}
+	options.EntityFilter = IDFilter{Include: c.StringSlice("include-entity"), Exclude: c.StringSlice("exclude-entity")}
+	if err = options.EntityFilter.Validate(); err != nil {
+		return
+	}
+	options.TileEntityFilter = IDFilter{
+		Include: c.StringSlice("include-tile-entity"),
+		Exclude: c.StringSlice("exclude-tile-entity"),
+	}
+	if err = options.TileEntityFilter.Validate(); err != nil {
+		return
+	}
+	if rulesFile := c.String("replace-blocks"); rulesFile != "" {
+		if options.Replacements, err = readReplacementRules(rulesFile); err != nil {
+			return
+		}
+	}
+	if biome := c.String("biome"); biome != "" {
+		if options.Biomes.Uniform, err = parseBiome(biome); err != nil {
+			return
+		}
+	}
+	if mappingFile := c.String("remap-biomes"); mappingFile != "" {
+		if options.Biomes.Mapping, err = readBiomeMapping(mappingFile); err != nil {
+			return
+		}
+	}
+	if options.Slime, err = slimeWriteOptionsFromFlags(c); err != nil {
+		return
+	}
+	if options.Flatten = c.Bool("flatten"); options.Flatten && options.Slime.Version != 0 && options.Slime.Version < 4 {
+		return options, fmt.Errorf("%w, which Slime version %d cannot store (use version 4 or later)",
+			ErrFlattenedWorld, options.Slime.Version)
+	}
+	if (options.RecomputeLight || options.RecomputeHeightMaps) && hasBlockStateRules(options.Replacements) {
+		return options, errors.New("rules for block states apply after light and height maps are recomputed from " +
+			"the legacy blocks (write them as ID:DATA instead)")
+	}
+	return
+}
+
+func anvilReadOptionsFromFlags(c *cli.Context) AnvilReadOptions {
+	return AnvilReadOptions{ClipHeight: c.Bool("clip-height")}
+}
+
+func slimeWriteOptionsFromFlags(c *cli.Context) (options SlimeWriteOptions, err error) {
+	ok, level := zstd.EncoderLevelFromString(c.String("compression-level"))
+	if !ok {
+		return options, fmt.Errorf("unknown compression level %q (expected fastest, default, better or best)",
+			c.String("compression-level"))
+	}
+	options.CompressionLevel = level
+
+	if window := c.String("compression-window"); window != "" {
+		if options.CompressionWindowSize, err = parseByteSize(window); err != nil {
+			return options, fmt.Errorf("invalid compression window: %w", err)
+		}
+	}
+
+	if options.CompressionConcurrency = c.Int("compression-concurrency"); options.CompressionConcurrency < 1 {
+		return options, errors.New("compression concurrency must be at least 1")
+	}
+
+	if c.IsSet("slime-version") {
+		version := c.Int("slime-version")
+		if version < slimeOldestWritableVersion || version > slimeLatestVersion {
+			return options, fmt.Errorf("%w %d (expected %d to %d)", ErrUnsupportedSlimeVersion, version,
+				slimeOldestWritableVersion, slimeLatestVersion)
+		}
+		options.Version = uint8(version)
+	}
+	if options.Light, err = ParseLightMode(c.String("light")); err != nil {
+		return
+	}
+	err = options.Validate()
+	return
+}
+
+// parseChunkCoord parses a pair of chunk coordinates written as X,Z.
+func parseChunkCoord(coord string) (ChunkCoord, error) {
+	parts := strings.Split(coord, ",")
+	if len(parts) != 2 {
+		return ChunkCoord{}, fmt.Errorf("%q is not of the form X,Z", coord)
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return ChunkCoord{}, fmt.Errorf("%q is not of the form X,Z", coord)
+	}
+	z, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return ChunkCoord{}, fmt.Errorf("%q is not of the form X,Z", coord)
+	}
+	return ChunkCoord{X: x, Z: z}, nil
+}
+
+// parseByteSize parses a size such as 4194304, 4096KiB or 4MiB.
+func parseByteSize(size string) (int, error) {
+	multiplier := 1
+	number := size
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int
+	}{{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"K", 1 << 10}, {"M", 1 << 20}} {
+		if strings.HasSuffix(size, unit.suffix) {
+			number = strings.TrimSuffix(size, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(number))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a valid size", size)
+	}
+	return n * multiplier, nil
+}
+
+// exitOnRepeatedInterrupt exits immediately on the second SIGINT or SIGTERM. The first one cancels the context the
+// CLI hands to each action, which gives loading and writing a chance to stop and clean up after themselves.
+func exitOnRepeatedInterrupt() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		<-signals
+		os.Exit(exitInterrupted)
+	}()
+}
+
+func loadAnvilWorld(ctx context.Context, path string, options AnvilReadOptions) (*AnvilWorld, error) {
+	startAnvilLoad := time.Now()
+	world, err := OpenAnvilWorld(ctx, filepath.Join(path, "region"), options, newProgressReporter())
+	if err != nil {
+		return nil, err
+	}
 	loadAnvilDuration := time.Now().Sub(startAnvilLoad).Milliseconds()
-	fmt.Printf("Anvil world loaded in %dms\n", loadAnvilDuration)
+	logger.Info("anvil world loaded", "duration_ms", loadAnvilDuration)
+	return world, nil
+}
+
+// prepareWorld applies the requested transformations to a loaded world and checks that the result can be written.
+func prepareWorld(world *AnvilWorld, options conversionOptions) error {
+	if err := transformWorld(world, options); err != nil {
+		return err
+	}
+
+	if columns := world.customBiomeColumns(); columns > 0 {
+		logger.Warn("biomes without a numeric id load as plains and are kept by name in the extra data; "+
+			"map them with --remap-biomes", "columns", columns)
+	}
 
+	err := world.ValidateSlimeBounds()
+	if errors.Is(err, ErrSlimeOutOfBounds) && options.Recenter {
+		dx, dz := world.Recenter()
+		logger.Info("recentered world", "offset_chunks_x", dx, "offset_chunks_z", dz)
+		err = world.ValidateSlimeBounds()
+	}
+	return err
+}
+
+// transformWorld applies the requested transformations to a loaded world.
+func transformWorld(world *AnvilWorld, options conversionOptions) error {
+	flattened := world.IsFlattened()
+	if flattened && (len(options.Orientations) > 0 || options.RecomputeLight || options.RecomputeHeightMaps) {
+		return fmt.Errorf("%w; transforms and recomputing light only work on legacy blocks", ErrFlattenedWorld)
+	}
+	if err := world.CheckReplacementRules(options.Replacements, options.Flatten); err != nil {
+		return err
+	}
+	if !options.ChunkFilter.empty() {
+		dropped := world.FilterChunks(options.ChunkFilter)
+		for _, reason := range dropped.IDs() {
+			logger.Info("dropped chunks", "reason", reason, "count", dropped[reason])
+		}
+		logger.Info("filtered chunks", "dropped", dropped.Total(), "kept", len(world.chunks))
+	}
+	for _, orientation := range options.Orientations {
+		world.Reorient(orientation)
+		logger.Info("transformed world", "transform", orientation.String())
+	}
+	if options.Offset != (ChunkCoord{}) {
+		world.Relocate(options.Offset.X, options.Offset.Z)
+		logger.Info("relocated world", "offset_chunks_x", options.Offset.X, "offset_chunks_z", options.Offset.Z)
+	}
+	if !options.EntityFilter.empty() || !options.TileEntityFilter.empty() {
+		removedEntities, removedTileEntities := world.FilterEntities(options.EntityFilter, options.TileEntityFilter)
+		logRemovedIDs("entities", removedEntities)
+		logRemovedIDs("tile entities", removedTileEntities)
+	}
+	var legacyCounts []int
+	if !flattened && len(options.Replacements) > 0 {
+		// Rules for legacy blocks apply before light and height maps are recomputed from the blocks, and before
+		// flattening, so that they see the blocks they are written for.
+		legacyCounts = world.ReplaceBlocks(options.Replacements)
+	}
+	if options.RecomputeLight {
+		start := time.Now()
+		world.RecomputeLight()
+		logger.Info("recomputed light", "duration_ms", time.Now().Sub(start).Milliseconds())
+	} else if options.RecomputeHeightMaps {
+		world.RecomputeHeightMaps()
+		logger.Info("recomputed height maps")
+	}
+	if options.Flatten {
+		start := time.Now()
+		world.Flatten()
+		logger.Info("flattened world", "duration_ms", time.Now().Sub(start).Milliseconds())
+	}
+	if len(options.Replacements) > 0 {
+		counts := legacyCounts
+		if flattened || options.Flatten {
+			// Rules for block states apply once the world has them.
+			counts = world.ReplaceBlocks(options.Replacements)
+			for i := range legacyCounts {
+				counts[i] += legacyCounts[i]
+			}
+		}
+		for i, rule := range options.Replacements {
+			logger.Info("replaced blocks", "rule", rule.Text, "count", counts[i])
+		}
+	}
+	if !options.Biomes.empty() {
+		logger.Info("remapped biomes", "columns", world.RemapBiomes(options.Biomes))
+	}
+	return nil
+}
+
+func readReplacementRules(path string) ([]ReplacementRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rules, err := ParseReplacementRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func readBiomeMapping(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	mapping, err := ParseBiomeMapping(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return mapping, nil
+}
+
+func logRemovedIDs(kind string, removed RemovedIDs) {
+	for _, id := range removed.IDs() {
+		logger.Info("removed "+kind, "id", id, "count", removed[id])
+	}
+	logger.Info("filtered "+kind, "removed", removed.Total())
+}
+
+func benchmarkAnvilWorld(ctx context.Context, path string, options conversionOptions) error {
+	world, err := loadAnvilWorld(ctx, path, options.Anvil)
+	if err != nil {
+		return err
+	}
+	if err = prepareWorld(world, options); err != nil {
+		return err
+	}
+	results, err := world.BenchmarkCompression(ctx, options.Slime)
+	if err != nil {
+		return err
+	}
+	return printCompressionBenchmarks(os.Stdout, results)
+}
+
+func processAnvilWorld(ctx context.Context, path string, options conversionOptions) (err error) {
+	saveTo := options.Output
 	if saveTo == "" {
 		saveTo = filepath.Join(filepath.Dir(path), filepath.Base(path)+".slime")
 	}
-	outputFile, err := os.OpenFile(saveTo, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if options.NoClobber {
+		// Fail before spending time loading the world. createAtomicFile checks again in case this races.
+		if _, err = os.Lstat(saveTo); err == nil {
+			return fmt.Errorf("%s: %w", saveTo, ErrOutputExists)
+		}
+	}
+
+	world, err := loadAnvilWorld(ctx, path, options.Anvil)
 	if err != nil {
 		return err
 	}
+	if err = prepareWorld(world, options); err != nil {
+		return err
+	}
+
+	return saveSlimeWorld(ctx, world, saveTo, options)
+}
+
+func saveSlimeWorld(ctx context.Context, world *AnvilWorld, saveTo string, options conversionOptions) error {
+	outputFile, err := createAtomicFile(saveTo, options.NoClobber)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Abort()
+
 	startSlimeSave := time.Now()
-	if err = world.WriteAsSlime(outputFile); err != nil {
+	if err = world.WriteAsSlime(ctx, outputFile, options.Slime); err != nil {
+		return err
+	}
+	if options.Verify {
+		if err = verifySlimeFile(ctx, world, outputFile, options.Slime); err != nil {
+			return fmt.Errorf("%s: %w", saveTo, err)
+		}
+	}
+	if err = outputFile.Commit(); err != nil {
 		return err
 	}
 	slimeSaveDuration := time.Now().Sub(startSlimeSave).Milliseconds()
-	fmt.Printf("Slime world saved in %dms\n", slimeSaveDuration)
+	logger.Info("slime world saved", "file", saveTo, "duration_ms", slimeSaveDuration)
+	return nil
+}
 
-	err = outputFile.Close()
-	return
+// verifySlimeFile reads back a Slime file that was just written and compares it with the world.
+func verifySlimeFile(ctx context.Context, world *AnvilWorld, file *atomicFile, options SlimeWriteOptions) error {
+	start := time.Now()
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	readBack, err := ReadSlimeWorld(ctx, bufio.NewReader(file))
+	if err != nil {
+		return fmt.Errorf("%w: could not read it back: %v", ErrVerificationFailed, err)
+	}
+	if err = world.VerifySlime(readBack, options); err != nil {
+		return err
+	}
+	logger.Info("verified slime world", "chunks", len(readBack.chunks),
+		"duration_ms", time.Now().Sub(start).Milliseconds())
+	return nil
 }
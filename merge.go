@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MergePolicy decides what happens when two merged worlds both have a chunk at the same position.
+type MergePolicy int
+
+const (
+	// MergeError refuses to merge worlds that overlap.
+	MergeError MergePolicy = iota
+	// MergeFirstWins keeps the chunk that was merged first.
+	MergeFirstWins
+	// MergeLastWins replaces the chunk with the one merged last.
+	MergeLastWins
+	// MergeOverlay keeps both chunks, with each section of the chunk merged last replacing the section at the same
+	// height in the existing chunk.
+	MergeOverlay
+)
+
+var mergePolicyNames = map[string]MergePolicy{
+	"error":      MergeError,
+	"first-wins": MergeFirstWins,
+	"last-wins":  MergeLastWins,
+	"overlay":    MergeOverlay,
+}
+
+func ParseMergePolicy(name string) (MergePolicy, error) {
+	if policy, ok := mergePolicyNames[strings.ToLower(name)]; ok {
+		return policy, nil
+	}
+	return MergeError, fmt.Errorf("unknown merge policy %q (expected error, first-wins, last-wins or overlay)", name)
+}
+
+var ErrChunkOverlap = errors.New("chunks overlap")
+
+// Merge adds every chunk of other to the world, resolving chunks that exist in both according to policy. The chunks
+// of other are moved into the world rather than copied, so other should not be used afterwards.
+func (world *AnvilWorld) Merge(other *AnvilWorld, policy MergePolicy) error {
+	if policy == MergeError {
+		// Check everything first so that a failed merge leaves the world untouched.
+		for coord := range other.chunks {
+			if _, exists := world.chunks[coord]; exists {
+				return fmt.Errorf("%w: both worlds have chunk %d,%d", ErrChunkOverlap, coord.X, coord.Z)
+			}
+		}
+	}
+
+	if world.chunks == nil {
+		world.chunks = make(map[ChunkCoord]MinecraftChunk, len(other.chunks))
+	}
+	for coord, chunk := range other.chunks {
+		existing, exists := world.chunks[coord]
+		switch {
+		case !exists || policy == MergeLastWins:
+			world.chunks[coord] = chunk
+		case policy == MergeOverlay:
+			world.chunks[coord] = overlayChunk(existing, chunk)
+		}
+	}
+	return nil
+}
+
+// overlayChunk places the sections of top over those of bottom. Tile entities and scheduled ticks in the replaced
+// sections are replaced along with their blocks, entities from both chunks are kept, and the height map keeps the
+// highest block of each column.
+func overlayChunk(bottom, top MinecraftChunk) MinecraftChunk {
+	replaced := make(map[uint8]bool)
+	for _, section := range top.Sections {
+		replaced[section.Y] = true
+	}
+
+	result := top
+	result.Sections = nil
+	for _, section := range bottom.Sections {
+		if !replaced[section.Y] {
+			result.Sections = append(result.Sections, section)
+		}
+	}
+	result.Sections = append(result.Sections, top.Sections...)
+	sortSections(result.Sections)
+
+	result.TileEntities = append(outsideSections(bottom.TileEntities, replaced), top.TileEntities...)
+	result.TileTicks = append(outsideSections(bottom.TileTicks, replaced), top.TileTicks...)
+	result.LiquidTicks = append(outsideSections(bottom.LiquidTicks, replaced), top.LiquidTicks...)
+
+	result.Entities = append(append([]NBTCompound(nil), bottom.Entities...), top.Entities...)
+
+	if len(bottom.HeightMap) == len(top.HeightMap) {
+		result.HeightMap = maxHeights(bottom.HeightMap, top.HeightMap)
+	} else {
+		// Recomputed when the world is written.
+		result.HeightMap = nil
+	}
+	// Named height maps that only one of the chunks has are left out, and the server calculates them.
+	result.HeightMaps = nil
+	for name, heights := range top.HeightMaps {
+		if bottomHeights, ok := bottom.HeightMaps[name]; ok && len(bottomHeights) == len(heights) {
+			if result.HeightMaps == nil {
+				result.HeightMaps = make(map[string][]int)
+			}
+			result.HeightMaps[name] = maxHeights(bottomHeights, heights)
+		}
+	}
+	return result
+}
+
+// maxHeights returns the higher of the two heights of each column.
+func maxHeights(a, b []int) []int {
+	heights := make([]int, len(b))
+	for i := range b {
+		heights[i] = b[i]
+		if a[i] > heights[i] {
+			heights[i] = a[i]
+		}
+	}
+	return heights
+}
+
+// outsideSections returns the compounds, such as tile entities, whose y tag places them outside the given sections.
+func outsideSections(compounds []NBTCompound, sections map[uint8]bool) []NBTCompound {
+	var kept []NBTCompound
+	for _, compound := range compounds {
+		if y, ok := compound.Int("y"); ok && sections[uint8(y>>4)] {
+			continue
+		}
+		kept = append(kept, compound)
+	}
+	return kept
+}
+
+func sortSections(sections []MinecraftChunkSection) {
+	sort.Slice(sections, func(one, two int) bool {
+		return sections[one].Y < sections[two].Y
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+var mergeCommand = &cli.Command{
+	Name:      "merge",
+	Usage:     "merges several Anvil worlds or Slime files into one Slime world",
+	ArgsUsage: "SOURCE[@DX,DZ]...",
+	Description: "Each SOURCE is an Anvil world directory or a Slime file, optionally moved by DX,DZ chunks. Sources " +
+		"are merged in the order they are given, so with first-wins the earliest source wins and with last-wins " +
+		"or overlay the latest one does.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "writes the merged Slime world to `FILE`",
+		},
+		&cli.StringFlag{
+			Name:  "policy",
+			Value: "error",
+			Usage: "resolves chunks present in more than one source with `POLICY` (error, first-wins, last-wins or overlay)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			return errors.New("need at least one world to merge")
+		}
+		if c.String("output") == "" {
+			return errors.New("need an --output file for the merged world")
+		}
+		policy, err := ParseMergePolicy(c.String("policy"))
+		if err != nil {
+			return err
+		}
+		options, err := conversionOptionsFromFlags(c)
+		if err != nil {
+			return err
+		}
+
+		var sources []mergeSource
+		for _, arg := range c.Args().Slice() {
+			source, err := parseMergeSource(arg)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, source)
+		}
+		return mergeWorlds(c.Context, sources, policy, options)
+	},
+}
+
+type mergeSource struct {
+	Path   string
+	Offset ChunkCoord
+}
+
+// parseMergeSource parses a source of the form PATH or PATH@DX,DZ.
+func parseMergeSource(arg string) (mergeSource, error) {
+	at := strings.LastIndex(arg, "@")
+	if at == -1 {
+		return mergeSource{Path: arg}, nil
+	}
+	offset, err := parseChunkCoord(arg[at+1:])
+	if err != nil {
+		return mergeSource{}, fmt.Errorf("invalid offset for %s: %w", arg[:at], err)
+	}
+	return mergeSource{Path: arg[:at], Offset: offset}, nil
+}
+
+func mergeWorlds(ctx context.Context, sources []mergeSource, policy MergePolicy, options conversionOptions) error {
+	if options.NoClobber {
+		if _, err := os.Lstat(options.Output); err == nil {
+			return fmt.Errorf("%s: %w", options.Output, ErrOutputExists)
+		}
+	}
+
+	merged := &AnvilWorld{chunks: make(map[ChunkCoord]MinecraftChunk)}
+	for _, source := range sources {
+		start := time.Now()
+		world, err := OpenWorld(ctx, source.Path, options.Anvil, newProgressReporter())
+		if err != nil {
+			return fmt.Errorf("could not load %s: %w", source.Path, err)
+		}
+		world.Relocate(source.Offset.X, source.Offset.Z)
+		if err = merged.Merge(world, policy); err != nil {
+			return fmt.Errorf("could not merge %s: %w", source.Path, err)
+		}
+		logger.Info("merged world", "source", source.Path, "chunks", len(world.chunks),
+			"duration_ms", time.Since(start).Milliseconds())
+	}
+
+	if err := prepareWorld(merged, options); err != nil {
+		return err
+	}
+	return saveSlimeWorld(ctx, merged, options.Output, options)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerge_policies(t *testing.T) {
+	newWorld := func(marker byte) *AnvilWorld {
+		chunk := newTestChunk(0, 0)
+		chunk.Sections[0].Blocks[0] = marker
+		return &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{X: 0, Z: 0}: chunk}}
+	}
+
+	world := newWorld(1)
+	if err := world.Merge(newWorld(2), MergeError); !errors.Is(err, ErrChunkOverlap) {
+		t.Errorf("expected ErrChunkOverlap, got %v", err)
+	}
+
+	for policy, want := range map[MergePolicy]byte{MergeFirstWins: 1, MergeLastWins: 2} {
+		world := newWorld(1)
+		if err := world.Merge(newWorld(2), policy); err != nil {
+			t.Fatal(err)
+		}
+		if got := world.chunks[ChunkCoord{}].Sections[0].Blocks[0]; got != want {
+			t.Errorf("policy %d kept block %d, want %d", policy, got, want)
+		}
+	}
+}
+
+func TestMerge_overlay(t *testing.T) {
+	bottom := newTestChunk(0, 0)
+	bottom.Sections = append(bottom.Sections, MinecraftChunkSection{
+		Y:          2,
+		BlockLight: make([]byte, 2048),
+		Blocks:     make([]byte, 4096),
+		Data:       make([]byte, 2048),
+		SkyLight:   make([]byte, 2048),
+	})
+	bottom.Sections[0].Blocks[0] = 1
+	bottom.HeightMap[0] = 40
+	bottom.HeightMaps = map[string][]int{heightMapMotionBlocking: make([]int, 256), "OCEAN_FLOOR": make([]int, 256)}
+	bottom.HeightMaps[heightMapMotionBlocking][0] = 40
+	top := newTestChunk(0, 0)
+	top.Sections[0].Blocks[0] = 2
+	top.HeightMaps = map[string][]int{heightMapMotionBlocking: make([]int, 256), "WORLD_SURFACE": make([]int, 256)}
+	top.HeightMaps[heightMapMotionBlocking][1] = 20
+
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{}: bottom}}
+	if err := world.Merge(&AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{}: top}}, MergeOverlay); err != nil {
+		t.Fatal(err)
+	}
+
+	merged := world.chunks[ChunkCoord{}]
+	if len(merged.Sections) != 2 || merged.Sections[0].Y != 0 || merged.Sections[1].Y != 2 {
+		t.Fatalf("unexpected sections after overlay: %d", len(merged.Sections))
+	}
+	if merged.Sections[0].Blocks[0] != 2 {
+		t.Errorf("section 0 was not replaced by the top chunk")
+	}
+	if len(merged.TileEntities) != 1 {
+		t.Errorf("expected the tile entity in the replaced section to be replaced, got %d", len(merged.TileEntities))
+	}
+	if len(merged.Entities) != 2 {
+		t.Errorf("expected entities from both chunks, got %d", len(merged.Entities))
+	}
+	if merged.HeightMap[0] != 40 {
+		t.Errorf("height map was not kept at the highest block: %d", merged.HeightMap[0])
+	}
+	if motionBlocking := merged.HeightMaps[heightMapMotionBlocking]; len(merged.HeightMaps) != 1 ||
+		motionBlocking[0] != 40 || motionBlocking[1] != 20 {
+		t.Errorf("expected only the height map both chunks have, kept at the highest block, got %v",
+			merged.HeightMaps)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"math"
 	"reflect"
+	"sort"
 )
 
 func Marshal(w io.Writer, v interface{}) error {
@@ -296,9 +297,13 @@ func (e *Encoder) marshalStruct(val reflect.Value) error {
 }
 
 func (e *Encoder) marshalMap(val reflect.Value) error {
-	iter := val.MapRange()
-	for iter.Next() {
-		err := e.marshal(iter.Value(), iter.Key().String())
+	// Go randomizes map iteration order, so write the keys in sorted order to produce the same bytes every time.
+	keys := val.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, key := range keys {
+		err := e.marshal(val.MapIndex(key), key.String())
 		if err != nil {
 			return err
 		}
@@ -323,3 +323,24 @@ func TestUnmarshal_ErrorString(t *testing.T) {
 	t.Log(err)
 
 }
+
+func TestMarshal_mapKeyOrder(t *testing.T) {
+	value := map[string]interface{}{"b": byte(2), "c": byte(3), "a": byte(1)}
+	want := []byte{
+		0x0a, 0x00, 0x00,
+		0x01, 0x00, 0x01, 'a', 0x01,
+		0x01, 0x00, 0x01, 'b', 0x02,
+		0x01, 0x00, 0x01, 'c', 0x03,
+		0x00,
+	}
+
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		if err := Marshal(&buf, value); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatalf("Marshal map fail: get % 02x, want % 02x", buf.Bytes(), want)
+		}
+	}
+}
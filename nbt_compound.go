@@ -0,0 +1,70 @@
+package main
+
+// NBTCompound is an NBT compound whose contents are not modelled by a struct, such as an entity or a tile entity.
+// Nested compounds decode as map[string]interface{} and lists as []interface{}, so use the accessors below to walk
+// into them.
+type NBTCompound map[string]interface{}
+
+// ID returns the id tag of an entity or tile entity, or an empty string if there is none.
+func (c NBTCompound) ID() string {
+	id, _ := c["id"].(string)
+	return id
+}
+
+// Compound returns the nested compound with the given name.
+func (c NBTCompound) Compound(name string) (NBTCompound, bool) {
+	return asCompound(c[name])
+}
+
+// CompoundList returns the compounds in the list with the given name, skipping any entries that are not compounds.
+func (c NBTCompound) CompoundList(name string) []NBTCompound {
+	list, _ := c[name].([]interface{})
+	var compounds []NBTCompound
+	for _, entry := range list {
+		if compound, ok := asCompound(entry); ok {
+			compounds = append(compounds, compound)
+		}
+	}
+	return compounds
+}
+
+// Int returns an integer tag of any width as an int.
+func (c NBTCompound) Int(name string) (int, bool) {
+	switch v := c[name].(type) {
+	case byte:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// SetInt replaces an existing integer tag, keeping its original width. It does nothing if the tag does not exist.
+func (c NBTCompound) SetInt(name string, value int) {
+	switch c[name].(type) {
+	case byte:
+		c[name] = byte(value)
+	case int16:
+		c[name] = int16(value)
+	case int32:
+		c[name] = int32(value)
+	case int64:
+		c[name] = int64(value)
+	}
+}
+
+func asCompound(value interface{}) (NBTCompound, bool) {
+	switch v := value.(type) {
+	case NBTCompound:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	default:
+		return nil, false
+	}
+}
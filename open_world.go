@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// OpenWorld loads either an Anvil world, when path is a world directory, or a Slime file. options only apply to Anvil
+// worlds.
+func OpenWorld(ctx context.Context, path string, options AnvilReadOptions, progress ProgressReporter) (*AnvilWorld,
+	error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return OpenAnvilWorld(ctx, filepath.Join(path, "region"), options, progress)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadSlimeWorld(ctx, file)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressReporter receives updates while a world is being loaded. ChunkLoaded and RegionLoaded are called from the
+// region workers and may be called concurrently.
+type ProgressReporter interface {
+	// Begin is called once the number of regions to load is known.
+	Begin(regions int)
+	ChunkLoaded()
+	RegionLoaded(name string, chunks int)
+	// End is called once loading stops, whether or not it was successful.
+	End()
+}
+
+type noopProgressReporter struct{}
+
+func (noopProgressReporter) Begin(int)                {}
+func (noopProgressReporter) ChunkLoaded()             {}
+func (noopProgressReporter) RegionLoaded(string, int) {}
+func (noopProgressReporter) End()                     {}
+
+// progressCounter tracks what has been loaded so far and the rate it is being loaded at. The counters come first so
+// that they stay 64-bit aligned for atomic access on 32-bit platforms.
+type progressCounter struct {
+	totalRegions int64
+	regions      int64
+	chunks       int64
+	started      time.Time
+}
+
+func (c *progressCounter) begin(regions int) {
+	c.started = time.Now()
+	atomic.StoreInt64(&c.totalRegions, int64(regions))
+}
+
+func (c *progressCounter) snapshot() (regions, totalRegions, chunks int64, chunksPerSecond float64) {
+	regions = atomic.LoadInt64(&c.regions)
+	totalRegions = atomic.LoadInt64(&c.totalRegions)
+	chunks = atomic.LoadInt64(&c.chunks)
+	if elapsed := time.Since(c.started).Seconds(); elapsed > 0 {
+		chunksPerSecond = float64(chunks) / elapsed
+	}
+	return
+}
+
+// logProgressReporter logs each loaded region at the debug level and a summary once loading ends.
+type logProgressReporter struct {
+	progressCounter
+	logger *Logger
+}
+
+func newLogProgressReporter(logger *Logger) *logProgressReporter {
+	return &logProgressReporter{logger: logger}
+}
+
+func (r *logProgressReporter) Begin(regions int) {
+	r.begin(regions)
+	r.logger.Info("loading regions", "regions", regions)
+}
+
+func (r *logProgressReporter) ChunkLoaded() {
+	atomic.AddInt64(&r.chunks, 1)
+}
+
+func (r *logProgressReporter) RegionLoaded(name string, chunks int) {
+	done := atomic.AddInt64(&r.regions, 1)
+	r.logger.Debug("region loaded", "region", name, "chunks", chunks, "progress",
+		fmt.Sprintf("%d/%d", done, atomic.LoadInt64(&r.totalRegions)))
+}
+
+func (r *logProgressReporter) End() {
+	regions, totalRegions, chunks, rate := r.snapshot()
+	r.logger.Info("finished loading regions", "regions", regions, "total_regions", totalRegions,
+		"chunks", chunks, "chunks_per_second", int64(rate))
+}
+
+const progressBarWidth = 30
+
+// ttyProgressBar draws a progress bar that redraws itself in place on a terminal.
+type ttyProgressBar struct {
+	progressCounter
+	out  io.Writer
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newTTYProgressBar(out io.Writer) *ttyProgressBar {
+	return &ttyProgressBar{out: out}
+}
+
+func (b *ttyProgressBar) Begin(regions int) {
+	b.begin(regions)
+	b.stop = make(chan struct{})
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.draw()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (b *ttyProgressBar) ChunkLoaded() {
+	atomic.AddInt64(&b.chunks, 1)
+}
+
+func (b *ttyProgressBar) RegionLoaded(string, int) {
+	atomic.AddInt64(&b.regions, 1)
+}
+
+func (b *ttyProgressBar) End() {
+	if b.stop == nil {
+		return
+	}
+	close(b.stop)
+	b.wg.Wait()
+	b.draw()
+	_, _ = fmt.Fprintln(b.out)
+}
+
+func (b *ttyProgressBar) draw() {
+	regions, totalRegions, chunks, rate := b.snapshot()
+	filled := progressBarWidth
+	if totalRegions > 0 {
+		filled = int(regions * progressBarWidth / totalRegions)
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	_, _ = fmt.Fprintf(b.out, "\r[%s] %d/%d regions, %d chunks, %.0f chunks/s", bar, regions, totalRegions, chunks, rate)
+}
+
+// isTerminal reports whether the file is attached to a terminal.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+// RenderOptions controls how a world is drawn by Render.
+type RenderOptions struct {
+	// Scale is the width and height of each block, in pixels.
+	Scale int
+	// Shading darkens blocks that are lower than the block to their north and brightens ones that are higher, the way
+	// maps in the game do.
+	Shading bool
+	// ChunkGrid draws a line along the north and west edge of every chunk.
+	ChunkGrid bool
+}
+
+// Brightness of a block compared to the block north of it, out of 255, as maps in the game shade them.
+const (
+	renderBrightnessHigher = 255
+	renderBrightnessLevel  = 220
+	renderBrightnessLower  = 180
+)
+
+// Render draws the world from above, showing the color of the top block of every column. Columns without chunks, or
+// without any blocks, are transparent.
+func (world *AnvilWorld) Render(options RenderOptions) (*image.RGBA, error) {
+	if len(world.chunks) == 0 {
+		return nil, errors.New("world has no chunks to render")
+	}
+	if options.Scale < 1 {
+		return nil, errors.New("render scale must be at least 1")
+	}
+
+	minChunkXZ, width, depth := world.determineChunkBounds()
+	blocksWide, blocksDeep := width*16, depth*16
+	colors := make([]color.RGBA, blocksWide*blocksDeep)
+	heights := make([]int, blocksWide*blocksDeep)
+	legacyColors := legacyBlockColors()
+	for coord, chunk := range world.chunks {
+		surface := newChunkSurface(chunk, legacyColors)
+		originX, originZ := (coord.X-minChunkXZ.X)*16, (coord.Z-minChunkXZ.Z)*16
+		for z := 0; z < 16; z++ {
+			for x := 0; x < 16; x++ {
+				idx := (originZ+z)*blocksWide + originX + x
+				heights[idx], colors[idx] = surface.top(x, z)
+			}
+		}
+	}
+
+	if options.Shading {
+		shaded := make([]color.RGBA, len(colors))
+		for idx, c := range colors {
+			brightness := renderBrightnessLevel
+			if north := idx - blocksWide; north >= 0 && colors[north].A != 0 {
+				if heights[idx] > heights[north] {
+					brightness = renderBrightnessHigher
+				} else if heights[idx] < heights[north] {
+					brightness = renderBrightnessLower
+				}
+			}
+			shaded[idx] = scaleColor(c, brightness)
+		}
+		colors = shaded
+	}
+
+	scale := options.Scale
+	img := image.NewRGBA(image.Rect(0, 0, blocksWide*scale, blocksDeep*scale))
+	for py := 0; py < blocksDeep*scale; py++ {
+		for px := 0; px < blocksWide*scale; px++ {
+			c := colors[(py/scale)*blocksWide+px/scale]
+			if options.ChunkGrid && (px%(16*scale) == 0 || py%(16*scale) == 0) {
+				c = scaleColor(c, 128)
+				if c.A < 128 {
+					c.A = 128
+				}
+			}
+			img.SetRGBA(px, py, c)
+		}
+	}
+	return img, nil
+}
+
+// scaleColor multiplies the color channels of an opaque color by brightness/255.
+func scaleColor(c color.RGBA, brightness int) color.RGBA {
+	return color.RGBA{
+		R: uint8(int(c.R) * brightness / 255),
+		G: uint8(int(c.G) * brightness / 255),
+		B: uint8(int(c.B) * brightness / 255),
+		A: c.A,
+	}
+}
+
+// chunkSurface finds the top block of each column of a chunk.
+type chunkSurface struct {
+	heightMap    []int
+	sections     map[int]sectionColors
+	maxSectionY  int
+	legacyColors *[256 * 16]color.RGBA
+}
+
+// sectionColors looks up the color of each block in a section, whichever way it stores its blocks.
+type sectionColors struct {
+	legacy        *MinecraftChunkSection
+	paletteColors []color.RGBA
+	indices       []int
+}
+
+func newChunkSurface(chunk MinecraftChunk, legacyColors *[256 * 16]color.RGBA) *chunkSurface {
+	surface := &chunkSurface{
+		sections:     make(map[int]sectionColors, len(chunk.Sections)),
+		maxSectionY:  -1,
+		legacyColors: legacyColors,
+	}
+	if len(chunk.HeightMap) == 256 {
+		surface.heightMap = chunk.HeightMap
+	}
+	for i := range chunk.Sections {
+		section := &chunk.Sections[i]
+		colors := sectionColors{legacy: section}
+		if section.usesPalette() {
+			colors = sectionColors{
+				paletteColors: make([]color.RGBA, len(section.Palette)),
+				indices:       unpackBlockStates(section.BlockStates, len(section.BlockStates)/64, 4096),
+			}
+			for p, entry := range section.Palette {
+				colors.paletteColors[p] = blockColor(blockStateFromPaletteEntry(entry).Name)
+			}
+		}
+		surface.sections[int(section.Y)] = colors
+		if int(section.Y) > surface.maxSectionY {
+			surface.maxSectionY = int(section.Y)
+		}
+	}
+	return surface
+}
+
+// top returns the height and color of the highest block in a column that is not air. The search starts just below the
+// height map, where there is one, so blocks that let light through above the surface, such as glass and flowers, are
+// looked through. The height is -1 for columns with no blocks at all.
+func (s *chunkSurface) top(x, z int) (int, color.RGBA) {
+	y := s.maxSectionY<<4 | 15
+	if s.heightMap != nil && s.heightMap[z<<4|x] > 0 && s.heightMap[z<<4|x]-1 < y {
+		y = s.heightMap[z<<4|x] - 1
+	}
+	for ; y >= 0; y-- {
+		section, ok := s.sections[y>>4]
+		if !ok {
+			y &^= 15
+			continue
+		}
+		idx := (y&15)<<8 | z<<4 | x
+		var c color.RGBA
+		if section.legacy != nil {
+			c = s.legacyColors[int(section.legacy.Blocks[idx])<<4|int(getNibble(section.legacy.Data, idx))]
+		} else if p := section.indices[idx]; p < len(section.paletteColors) {
+			c = section.paletteColors[p]
+		}
+		if c.A != 0 {
+			return y, c
+		}
+	}
+	return -1, color.RGBA{}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+var renderCommand = &cli.Command{
+	Name:      "render",
+	Usage:     "draws a top-down map of a world as a PNG image",
+	ArgsUsage: "WORLD",
+	Description: "WORLD is an Anvil world directory or a Slime file. Each block of the map is colored after the top " +
+		"block of its column. The transformations given as global options are applied first, so the map shows the " +
+		"world as it would be converted.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "writes the image to `FILE` (default: next to the world)",
+		},
+		&cli.IntFlag{
+			Name:  "scale",
+			Value: 1,
+			Usage: "draws each block as `N` by N pixels",
+		},
+		&cli.BoolFlag{
+			Name:  "shading",
+			Usage: "shades blocks by their height, like maps in the game",
+		},
+		&cli.BoolFlag{
+			Name:  "grid",
+			Usage: "draws the border of every chunk",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return errors.New("need exactly one world to render")
+		}
+		options, err := conversionOptionsFromFlags(c)
+		if err != nil {
+			return err
+		}
+		renderOptions := RenderOptions{Scale: c.Int("scale"), Shading: c.Bool("shading"), ChunkGrid: c.Bool("grid")}
+		return renderWorld(c.Context, c.Args().First(), renderOptions, options)
+	},
+}
+
+func renderWorld(ctx context.Context, path string, renderOptions RenderOptions, options conversionOptions) error {
+	saveTo := options.Output
+	if saveTo == "" {
+		name := strings.TrimSuffix(filepath.Base(path), ".slime")
+		saveTo = filepath.Join(filepath.Dir(path), name+".png")
+	}
+
+	world, err := OpenWorld(ctx, path, options.Anvil, newProgressReporter())
+	if err != nil {
+		return err
+	}
+	if err = transformWorld(world, options); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	img, err := world.Render(renderOptions)
+	if err != nil {
+		return err
+	}
+	outputFile, err := createAtomicFile(saveTo, options.NoClobber)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Abort()
+	if err = png.Encode(outputFile, img); err != nil {
+		return err
+	}
+	if err = outputFile.Commit(); err != nil {
+		return err
+	}
+	logger.Info("rendered world", "file", saveTo, "width", img.Bounds().Dx(), "height", img.Bounds().Dy(),
+		"duration_ms", time.Now().Sub(start).Milliseconds())
+	return nil
+}
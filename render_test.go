@@ -0,0 +1,58 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestBlockColor(t *testing.T) {
+	for name, expected := range map[string]color.RGBA{
+		"minecraft:air":                   {},
+		"minecraft:stone":                 blockColors["stone"],
+		"minecraft:light_blue_wool":       dyeColors["light_blue"],
+		"minecraft:red_sandstone_stairs":  blockColors["red_sandstone"],
+		"minecraft:stone_brick_slab":      blockColors["stone_bricks"],
+		"minecraft:stripped_dark_oak_log": woodColors["dark_oak"].log,
+		"minecraft:oak_fence":             woodColors["oak"].planks,
+		"minecraft:diamond_ore":           blockColors["stone"],
+		"custom:machine":                  unknownBlockColor,
+	} {
+		if actual := blockColor(name); actual != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, actual)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	world := newTestWorld()
+	chunk := world.chunks[ChunkCoord{X: 0, Z: 0}]
+	chunk.Sections[0].Blocks[1<<8|5<<4|5] = 35
+	setNibble(chunk.Sections[0].Data, 1<<8|5<<4|5, 14)
+	world.chunks[ChunkCoord{X: 0, Z: 0}] = chunk
+
+	img, err := world.Render(RenderOptions{Scale: 2, Shading: true, ChunkGrid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 6*16*2 || img.Bounds().Dy() != 4*16*2 {
+		t.Fatalf("unexpected size %v", img.Bounds())
+	}
+
+	// Chunk 0,0 is the fourth chunk from the west and the third from the north.
+	originX, originZ := 3*16*2, 2*16*2
+	stone := scaleColor(blockColors["stone"], renderBrightnessLevel)
+	if actual := img.RGBAAt(originX+2*2, originZ+2*2); actual != stone {
+		t.Errorf("expected level stone %v, got %v", stone, actual)
+	}
+	wool := scaleColor(dyeColors["red"], renderBrightnessHigher)
+	if actual := img.RGBAAt(originX+5*2+1, originZ+5*2+1); actual != wool {
+		t.Errorf("expected raised red wool %v, got %v", wool, actual)
+	}
+	below := scaleColor(blockColors["stone"], renderBrightnessLower)
+	if actual := img.RGBAAt(originX+5*2, originZ+6*2); actual != below {
+		t.Errorf("expected stone below the wool %v, got %v", below, actual)
+	}
+	if grid := img.RGBAAt(originX, originZ+3); grid != scaleColor(stone, 128) {
+		t.Errorf("expected a grid line, got %v", grid)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// BlockPattern matches legacy blocks by id and data value, or block states by name and properties.
+type BlockPattern struct {
+	legacy bool
+
+	// Legacy patterns. A data value of -1 matches any data value.
+	id   byte
+	data int
+
+	// Block state patterns. The name may be a glob pattern, and a property of * matches any value.
+	state BlockState
+}
+
+// ParseBlockPattern parses a legacy block written as ID, ID:DATA or ID:*, or a block state written as
+// NAME[PROPERTY=VALUE,...], where the name may be a glob pattern and any value may be *.
+func ParseBlockPattern(text string) (BlockPattern, error) {
+	id, data, legacy, err := parseLegacyBlock(text)
+	if err != nil || legacy {
+		return BlockPattern{legacy: true, id: id, data: data}, err
+	}
+	state, err := ParseBlockState(text)
+	if err != nil {
+		return BlockPattern{}, err
+	}
+	if _, err = path.Match(state.Name, ""); err != nil {
+		return BlockPattern{}, fmt.Errorf("invalid block name pattern %q: %w", state.Name, err)
+	}
+	return BlockPattern{state: state}, nil
+}
+
+func (p BlockPattern) matchesLegacy(id, data byte) bool {
+	return p.legacy && id == p.id && (p.data < 0 || int(data) == p.data)
+}
+
+func (p BlockPattern) matchesState(state BlockState) bool {
+	if p.legacy {
+		return false
+	}
+	if matched, _ := path.Match(p.state.Name, state.Name); !matched {
+		return false
+	}
+	for name, value := range p.state.Properties {
+		if actual, ok := state.Properties[name]; !ok || (value != "*" && value != actual) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplacementRule replaces one kind of block with another. Rules either match legacy blocks by id and data value, or
+// block states by name and properties; a rule only applies to sections that store blocks the same way it does.
+type ReplacementRule struct {
+	// Text is the rule as it was written, for reporting.
+	Text string
+
+	from BlockPattern
+
+	// Legacy replacements. A data value of -1 keeps the data value of the block being replaced.
+	toID   byte
+	toData int
+
+	// Block state replacements. A property of * keeps the value of the block being replaced.
+	to BlockState
+}
+
+// ParseReplacementRules reads one rule per line, written as FROM -> TO. Blank lines and lines starting with # are
+// ignored. Legacy blocks are written as ID or ID:DATA, and block states as NAME[PROPERTY=VALUE,...].
+func ParseReplacementRules(reader io.Reader) ([]ReplacementRule, error) {
+	var rules []ReplacementRule
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := parseReplacementRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+func parseReplacementRule(text string) (ReplacementRule, error) {
+	parts := strings.Split(text, "->")
+	if len(parts) != 2 {
+		return ReplacementRule{}, fmt.Errorf("invalid rule %q (expected FROM -> TO)", text)
+	}
+	from, to := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	rule := ReplacementRule{Text: text}
+
+	var err error
+	if rule.from, err = ParseBlockPattern(from); err != nil {
+		return rule, err
+	}
+	toID, toData, toLegacy, err := parseLegacyBlock(to)
+	if err != nil {
+		return rule, err
+	}
+	if rule.from.legacy != toLegacy {
+		return rule, fmt.Errorf("invalid rule %q: cannot replace legacy blocks with block states or the other way around",
+			text)
+	}
+	if toLegacy {
+		rule.toID, rule.toData = toID, toData
+		return rule, nil
+	}
+
+	if rule.to, err = ParseBlockState(to); err != nil {
+		return rule, err
+	}
+	if strings.ContainsAny(rule.to.Name, "*?[") {
+		return rule, fmt.Errorf("invalid rule %q: the replacement must be a single block", text)
+	}
+	return rule, nil
+}
+
+// parseLegacyBlock parses ID, ID:DATA or ID:*, reporting whether the text is a legacy block at all.
+func parseLegacyBlock(text string) (id byte, data int, ok bool, err error) {
+	parts := strings.SplitN(text, ":", 2)
+	numericID, idErr := strconv.Atoi(parts[0])
+	if idErr != nil {
+		return 0, 0, false, nil
+	}
+	if numericID < 0 || numericID > 255 {
+		return 0, 0, true, fmt.Errorf("invalid block id %d (expected 0 to 255)", numericID)
+	}
+	data = -1
+	if len(parts) == 2 && parts[1] != "*" {
+		if data, err = strconv.Atoi(parts[1]); err != nil || data < 0 || data > 15 {
+			return 0, 0, true, fmt.Errorf("invalid data value %q (expected 0 to 15 or *)", parts[1])
+		}
+	}
+	return byte(numericID), data, true, nil
+}
+
+func (r ReplacementRule) replaceLegacy(data byte) (byte, byte) {
+	if r.toData < 0 {
+		return r.toID, data
+	}
+	return r.toID, byte(r.toData)
+}
+
+func (r ReplacementRule) replaceState(state BlockState) BlockState {
+	replaced := BlockState{Name: r.to.Name}
+	if len(r.to.Properties) > 0 {
+		replaced.Properties = make(map[string]string, len(r.to.Properties))
+		for name, value := range r.to.Properties {
+			if value == "*" {
+				if value = state.Properties[name]; value == "" {
+					continue
+				}
+			}
+			replaced.Properties[name] = value
+		}
+	}
+	return replaced
+}
+
+var ErrUnmatchableRule = errors.New("replacement rule can never match")
+
+// CheckReplacementRules reports rules that cannot match any block of the world: rules for legacy blocks in a world
+// that uses block states, and rules for block states in a legacy world, unless the world is going to be flattened.
+// Rules for legacy blocks apply before flattening, so they may be used with it.
+func (world *AnvilWorld) CheckReplacementRules(rules []ReplacementRule, flatten bool) error {
+	flattened := world.IsFlattened()
+	for _, rule := range rules {
+		switch {
+		case rule.from.legacy && flattened:
+			return fmt.Errorf("%w: %q replaces legacy block ids, but the world uses 1.13 block states (write it "+
+				"with block states instead)", ErrUnmatchableRule, rule.Text)
+		case !rule.from.legacy && !flattened && !flatten:
+			return fmt.Errorf("%w: %q replaces block states, but the world uses legacy block ids (flatten the "+
+				"world or write it as ID:DATA instead)", ErrUnmatchableRule, rule.Text)
+		}
+	}
+	return nil
+}
+
+// hasBlockStateRules reports whether any of the rules replaces block states rather than legacy blocks.
+func hasBlockStateRules(rules []ReplacementRule) bool {
+	for _, rule := range rules {
+		if !rule.from.legacy {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceBlocks applies the rules to every block in the world, using the first rule that matches each block, and
+// returns how many blocks each rule replaced. Chunks with replaced blocks lose their named height maps, which the
+// server calculates again, since the blocks they were taken from may have changed.
+func (world *AnvilWorld) ReplaceBlocks(rules []ReplacementRule) []int {
+	counts := make([]int, len(rules))
+	for coord, chunk := range world.chunks {
+		replaced := false
+		for i := range chunk.Sections {
+			section := &chunk.Sections[i]
+			if section.usesPalette() {
+				replaced = replaceSectionStates(section, rules, counts) || replaced
+			} else {
+				replaced = replaceSectionLegacy(section, rules, counts) || replaced
+			}
+		}
+		if replaced {
+			chunk.HeightMaps = nil
+		}
+		world.chunks[coord] = chunk
+	}
+	return counts
+}
+
+func replaceSectionLegacy(section *MinecraftChunkSection, rules []ReplacementRule, counts []int) (replaced bool) {
+	for idx, id := range section.Blocks {
+		data := getNibble(section.Data, idx)
+		for i, rule := range rules {
+			if rule.from.matchesLegacy(id, data) {
+				newID, newData := rule.replaceLegacy(data)
+				section.Blocks[idx] = newID
+				setNibble(section.Data, idx, newData)
+				counts[i]++
+				replaced = true
+				break
+			}
+		}
+	}
+	return
+}
+
+func replaceSectionStates(section *MinecraftChunkSection, rules []ReplacementRule, counts []int) bool {
+	// Rules are matched against each palette entry once rather than against every block.
+	replacements := make([]int, len(section.Palette))
+	anyReplaced := false
+	for p, entry := range section.Palette {
+		replacements[p] = -1
+		state := blockStateFromPaletteEntry(entry)
+		for i, rule := range rules {
+			if rule.from.matchesState(state) {
+				replacements[p] = i
+				anyReplaced = true
+				break
+			}
+		}
+	}
+	if !anyReplaced {
+		return false
+	}
+
+	states := section.blockStates()
+	bits := len(section.BlockStates) / 64
+	replaced := false
+	for idx, p := range unpackBlockStates(section.BlockStates, bits, len(states)) {
+		if p < len(replacements) && replacements[p] >= 0 {
+			rule := rules[replacements[p]]
+			states[idx] = rule.replaceState(states[idx])
+			counts[replacements[p]]++
+			replaced = true
+		}
+	}
+	section.setBlockStates(states)
+	return replaced
+}
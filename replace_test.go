@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseReplacementRules(t *testing.T) {
+	rules, err := ParseReplacementRules(strings.NewReader(`
+# barriers become air
+166 -> 0
+35:14 -> 35:*
+minecraft:*_ore -> minecraft:stone
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(rules))
+	}
+
+	for _, invalid := range []string{"166", "166 -> minecraft:air", "300 -> 0", "35:16 -> 0", "stone -> *_ore"} {
+		if _, err = ParseReplacementRules(strings.NewReader(invalid)); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestReplaceBlocks(t *testing.T) {
+	rules, err := ParseReplacementRules(strings.NewReader(`
+1 -> 166
+minecraft:oak_log[axis=*] -> minecraft:birch_log[axis=*]
+minecraft:*_ore -> minecraft:stone
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := newTestWorld()
+	counts := legacy.ReplaceBlocks(rules)
+	if expected := 256 * len(legacy.chunks); counts[0] != expected || counts[1] != 0 {
+		t.Errorf("expected %d legacy replacements, got %v", expected, counts)
+	}
+	for _, chunk := range legacy.chunks {
+		if chunk.Sections[0].Blocks[0] != 166 {
+			t.Fatalf("block was not replaced: %d", chunk.Sections[0].Blocks[0])
+		}
+	}
+
+	section := MinecraftChunkSection{}
+	states := make([]BlockState, 4096)
+	for i := range states {
+		switch i % 3 {
+		case 0:
+			states[i] = blockState("oak_log", "axis", "x")
+		case 1:
+			states[i] = blockState("coal_ore")
+		default:
+			states[i] = blockState("air")
+		}
+	}
+	section.setBlockStates(states)
+	untouched := MinecraftChunkSection{}
+	untouched.setBlockStates(make([]BlockState, 4096))
+	heightMaps := map[string][]int{heightMapMotionBlocking: make([]int, 256)}
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{
+		{}:           {Sections: []MinecraftChunkSection{section}, HeightMaps: heightMaps},
+		{X: 1, Z: 0}: {X: 1, Sections: []MinecraftChunkSection{untouched}, HeightMaps: heightMaps},
+	}}
+	counts = world.ReplaceBlocks(rules)
+	if counts[0] != 0 || counts[1] != 1366 || counts[2] != 1365 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	replaced := world.chunks[ChunkCoord{}].Sections[0].blockStates()
+	if replaced[0].String() != "minecraft:birch_log[axis=x]" || replaced[1].String() != "minecraft:stone" {
+		t.Errorf("unexpected blocks %s and %s", replaced[0], replaced[1])
+	}
+	if world.chunks[ChunkCoord{}].HeightMaps != nil || world.chunks[ChunkCoord{X: 1, Z: 0}].HeightMaps == nil {
+		t.Error("expected only the chunk with replaced blocks to lose its height maps")
+	}
+}
+
+func TestCheckReplacementRules(t *testing.T) {
+	rules, err := ParseReplacementRules(strings.NewReader("166 -> 0\nminecraft:barrier -> minecraft:air\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := newTestWorld()
+	if err = legacy.CheckReplacementRules(rules, true); err != nil {
+		t.Errorf("expected both rules to apply to a world that is flattened, got %v", err)
+	}
+	if err = legacy.CheckReplacementRules(rules, false); !errors.Is(err, ErrUnmatchableRule) ||
+		!strings.Contains(err.Error(), "minecraft:barrier") {
+		t.Errorf("expected the block state rule to be rejected for a legacy world, got %v", err)
+	}
+	flattened := newTestWorld()
+	flattened.Flatten()
+	if err = flattened.CheckReplacementRules(rules[1:], false); err != nil {
+		t.Errorf("expected the block state rule to apply to a flattened world, got %v", err)
+	}
+	if err = flattened.CheckReplacementRules(rules, true); !errors.Is(err, ErrUnmatchableRule) ||
+		!strings.Contains(err.Error(), "166 -> 0") {
+		t.Errorf("expected the legacy rule to be rejected for a flattened world, got %v", err)
+	}
+}
+
+func TestTransformWorld_replaceAndFlatten(t *testing.T) {
+	rules, err := ParseReplacementRules(strings.NewReader("1 -> 166\nminecraft:barrier -> minecraft:glass\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := newTestWorld()
+	if err = transformWorld(world, conversionOptions{Flatten: true, Replacements: rules}); err != nil {
+		t.Fatal(err)
+	}
+	if states := world.chunks[ChunkCoord{}].Sections[0].blockStates(); states[0].Name != "minecraft:glass" {
+		t.Errorf("expected stone to become barriers before flattening and glass after, got %s", states[0])
+	}
+}
+
+func TestTransformWorld_replaceBeforeRecomputing(t *testing.T) {
+	rules, err := ParseReplacementRules(strings.NewReader("89 -> 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := newTestChunk(0, 0)
+	glowstone := 1<<8 | 8<<4 | 8
+	chunk.Sections[0].Blocks[glowstone] = 89
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{}: chunk}}
+	if err = transformWorld(world, conversionOptions{RecomputeLight: true, Replacements: rules}); err != nil {
+		t.Fatal(err)
+	}
+	section := world.chunks[ChunkCoord{}].Sections[0]
+	if block, light := section.Blocks[glowstone], getNibble(section.BlockLight, glowstone+1); block != 1 || light != 0 {
+		t.Errorf("expected the glowstone to be replaced before light was recomputed, got block %d with light %d "+
+			"next to it", block, light)
+	}
+
+	rules, err = ParseReplacementRules(strings.NewReader("1 -> 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world = &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{}: newTestChunk(0, 0)}}
+	if err = transformWorld(world, conversionOptions{RecomputeHeightMaps: true, Replacements: rules}); err != nil {
+		t.Fatal(err)
+	}
+	if height := world.chunks[ChunkCoord{}].HeightMap[0]; height != 0 {
+		t.Errorf("expected the height map to be recomputed after the floor was removed, got %d", height)
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Orientation is a rotation or mirror that can be applied to a whole world. Rotations are clockwise when looking down
+// on the world. Mirroring on X flips east and west; mirroring on Z flips north and south. Both rotations and mirrors
+// happen around the world origin, so combine them with Relocate to move the result where it is needed.
+type Orientation int
+
+const (
+	OrientationIdentity Orientation = iota
+	OrientationRotate90
+	OrientationRotate180
+	OrientationRotate270
+	OrientationMirrorX
+	OrientationMirrorZ
+)
+
+var orientationNames = map[string]Orientation{
+	"rotate90":  OrientationRotate90,
+	"rotate180": OrientationRotate180,
+	"rotate270": OrientationRotate270,
+	"mirror-x":  OrientationMirrorX,
+	"mirror-z":  OrientationMirrorZ,
+}
+
+func ParseOrientation(name string) (Orientation, error) {
+	if orientation, ok := orientationNames[strings.ToLower(name)]; ok {
+		return orientation, nil
+	}
+	return OrientationIdentity, fmt.Errorf("unknown transform %q (expected rotate90, rotate180, rotate270, mirror-x or mirror-z)", name)
+}
+
+func (o Orientation) String() string {
+	for name, orientation := range orientationNames {
+		if orientation == o {
+			return name
+		}
+	}
+	return "identity"
+}
+
+// apply maps a point relative to the origin.
+func (o Orientation) apply(x, z float64) (float64, float64) {
+	switch o {
+	case OrientationRotate90:
+		return -z, x
+	case OrientationRotate180:
+		return -x, -z
+	case OrientationRotate270:
+		return z, -x
+	case OrientationMirrorX:
+		return -x, z
+	case OrientationMirrorZ:
+		return x, -z
+	default:
+		return x, z
+	}
+}
+
+func (o Orientation) transformCell(x, z int) (int, int) {
+	// Transform the center of the cell so that it ends up covering the same area after being mapped.
+	nx, nz := o.apply(float64(x)+0.5, float64(z)+0.5)
+	return int(math.Floor(nx)), int(math.Floor(nz))
+}
+
+func (o Orientation) transformPoint(x, z float64) (float64, float64) {
+	return o.apply(x, z)
+}
+
+// transformYaw maps an entity's yaw, where 0 faces south and 90 faces west. The result is kept between 0 and 360.
+func (o Orientation) transformYaw(yaw float32) float32 {
+	switch o {
+	case OrientationRotate90:
+		yaw += 90
+	case OrientationRotate180:
+		yaw += 180
+	case OrientationRotate270:
+		yaw += 270
+	case OrientationMirrorX:
+		yaw = -yaw
+	case OrientationMirrorZ:
+		yaw = 180 - yaw
+	}
+	yaw = float32(math.Mod(float64(yaw), 360))
+	if yaw < 0 {
+		yaw += 360
+	} else if yaw == 0 {
+		yaw = 0 // mirroring can produce negative zero
+	}
+	return yaw
+}
+
+// direction is a horizontal direction. The values are ordered clockwise.
+type direction int
+
+const (
+	north direction = iota
+	east
+	south
+	west
+)
+
+func (o Orientation) transformDirection(d direction) direction {
+	switch o {
+	case OrientationRotate90:
+		return (d + 1) % 4
+	case OrientationRotate180:
+		return (d + 2) % 4
+	case OrientationRotate270:
+		return (d + 3) % 4
+	case OrientationMirrorX:
+		return (4 - d) % 4
+	case OrientationMirrorZ:
+		return (6 - d) % 4
+	default:
+		return d
+	}
+}
+
+// transformRotation16 maps the 16-step rotations used by signs, banners and skulls, where 0 faces south and the
+// value increases clockwise.
+func (o Orientation) transformRotation16(rotation int) int {
+	switch o {
+	case OrientationRotate90:
+		rotation += 4
+	case OrientationRotate180:
+		rotation += 8
+	case OrientationRotate270:
+		rotation += 12
+	case OrientationMirrorX:
+		rotation = 16 - rotation
+	case OrientationMirrorZ:
+		rotation = 24 - rotation
+	}
+	return rotation & 15
+}
+
+func (o Orientation) isMirror() bool {
+	return o == OrientationMirrorX || o == OrientationMirrorZ
+}
+
+// swapsAxes reports whether the X and Z axes trade places.
+func (o Orientation) swapsAxes() bool {
+	return o == OrientationRotate90 || o == OrientationRotate270
+}
+
+// Reorient rotates or mirrors the whole world around the origin: chunk positions, the blocks, light, height map and
+// biomes inside each chunk, the data of blocks that face a direction, and the positions and facing of entities and
+// tile entities.
+func (world *AnvilWorld) Reorient(o Orientation) {
+	if o == OrientationIdentity {
+		return
+	}
+
+	reoriented := make(map[ChunkCoord]MinecraftChunk, len(world.chunks))
+	for _, chunk := range world.chunks {
+		chunk.X, chunk.Z = o.transformCell(chunk.X, chunk.Z)
+		for i := range chunk.Sections {
+			reorientSection(&chunk.Sections[i], o)
+		}
+		chunk.HeightMap = reorientHeightMap(chunk.HeightMap, o)
+		if chunk.HeightMaps != nil {
+			heightMaps := make(map[string][]int, len(chunk.HeightMaps))
+			for name, heights := range chunk.HeightMaps {
+				heightMaps[name] = reorientHeightMap(heights, o)
+			}
+			chunk.HeightMaps = heightMaps
+		}
+		chunk.Biomes = reorientBiomes(chunk.Biomes, o)
+		chunk.CustomBiomes = reorientCustomBiomes(chunk.CustomBiomes, o)
+		chunk.BiomeCells = reorientBiomeCells(chunk.BiomeCells, o)
+		chunk.CustomBiomeCells = reorientCustomBiomeCells(chunk.CustomBiomeCells, o)
+		transformChunkEntities(&chunk, o)
+		for _, tileEntity := range chunk.TileEntities {
+			reorientTileEntity(tileEntity, o)
+		}
+		for _, entity := range chunk.Entities {
+			reorientHangingEntity(entity, o)
+		}
+		reoriented[ChunkCoord{X: chunk.X, Z: chunk.Z}] = chunk
+	}
+	world.chunks = reoriented
+}
+
+// reorientedIndex maps an index into an array laid out as YZX, with 16 blocks along each horizontal axis.
+func reorientedIndex(idx int, o Orientation) int {
+	x, z := o.transformCell(idx&15, (idx>>4)&15)
+	return idx&^0xff | (z&15)<<4 | x&15
+}
+
+func reorientHeightMap(heightMap []int, o Orientation) []int {
+	if len(heightMap) != 256 {
+		return heightMap
+	}
+	result := make([]int, len(heightMap))
+	for i, height := range heightMap {
+		result[reorientedIndex(i, o)] = height
+	}
+	return result
+}
+
+func reorientBiomes(biomes []byte, o Orientation) []byte {
+	if len(biomes) != 256 {
+		return biomes
+	}
+	result := make([]byte, len(biomes))
+	for i, biome := range biomes {
+		result[reorientedIndex(i, o)] = biome
+	}
+	return result
+}
+
+func reorientCustomBiomes(biomes map[int]string, o Orientation) map[int]string {
+	if biomes == nil {
+		return nil
+	}
+	result := make(map[int]string, len(biomes))
+	for i, name := range biomes {
+		result[reorientedIndex(i, o)] = name
+	}
+	return result
+}
+
+// reorientedCellIndex maps an index into biome cells, laid out as YZX with 4 cells along each horizontal axis, by
+// mapping a column of the cell.
+func reorientedCellIndex(idx int, o Orientation) int {
+	column := reorientedIndex((idx>>2&3)<<6|(idx&3)<<2, o)
+	return idx&^15 | (column>>6&3)<<2 | column>>2&3
+}
+
+func reorientBiomeCells(cells []byte, o Orientation) []byte {
+	if len(cells) != biomeCellsPerChunk {
+		return cells
+	}
+	result := make([]byte, len(cells))
+	for i, biome := range cells {
+		result[reorientedCellIndex(i, o)] = biome
+	}
+	return result
+}
+
+func reorientCustomBiomeCells(cells map[int]string, o Orientation) map[int]string {
+	if cells == nil {
+		return nil
+	}
+	result := make(map[int]string, len(cells))
+	for i, name := range cells {
+		result[reorientedCellIndex(i, o)] = name
+	}
+	return result
+}
+
+func reorientSection(section *MinecraftChunkSection, o Orientation) {
+	blocks := make([]byte, len(section.Blocks))
+	for i, block := range section.Blocks {
+		blocks[reorientedIndex(i, o)] = block
+	}
+	section.Blocks = blocks
+	section.Data = reorientNibbles(section.Data, o)
+	section.BlockLight = reorientNibbles(section.BlockLight, o)
+	section.SkyLight = reorientNibbles(section.SkyLight, o)
+
+	for i, block := range section.Blocks {
+		data := getNibble(section.Data, i)
+		if remapped := reorientLegacyData(block, data, o); remapped != data {
+			setNibble(section.Data, i, remapped)
+		}
+	}
+}
+
+func reorientNibbles(nibbles []byte, o Orientation) []byte {
+	if len(nibbles) != 2048 {
+		return nibbles
+	}
+	result := make([]byte, len(nibbles))
+	for i := 0; i < 4096; i++ {
+		setNibble(result, reorientedIndex(i, o), getNibble(nibbles, i))
+	}
+	return result
+}
+
+func getNibble(nibbles []byte, idx int) byte {
+	if idx&1 == 0 {
+		return nibbles[idx>>1] & 0x0f
+	}
+	return nibbles[idx>>1] >> 4
+}
+
+func setNibble(nibbles []byte, idx int, value byte) {
+	if idx&1 == 0 {
+		nibbles[idx>>1] = nibbles[idx>>1]&0xf0 | value&0x0f
+	} else {
+		nibbles[idx>>1] = nibbles[idx>>1]&0x0f | value<<4
+	}
+}
+
+// legacyFacing describes how a legacy block stores the direction it faces in its data value.
+type legacyFacing struct {
+	mask byte
+	// values holds the data value for north, east, south and west, in that order.
+	values [4]byte
+}
+
+func (f legacyFacing) reorient(data byte, o Orientation) byte {
+	for d, value := range f.values {
+		if data&f.mask == value {
+			return data&^f.mask | f.values[o.transformDirection(direction(d))]
+		}
+	}
+	return data
+}
+
+var (
+	stairsFacing    = legacyFacing{mask: 3, values: [4]byte{3, 0, 2, 1}}
+	doorFacing      = legacyFacing{mask: 3, values: [4]byte{3, 0, 1, 2}}
+	wallFacing      = legacyFacing{mask: 7, values: [4]byte{2, 5, 3, 4}}
+	torchFacing     = legacyFacing{mask: 7, values: [4]byte{4, 1, 3, 2}}
+	horizontalSWNE  = legacyFacing{mask: 3, values: [4]byte{2, 3, 0, 1}}
+	redstoneFacing  = legacyFacing{mask: 3, values: [4]byte{0, 1, 2, 3}}
+	legacyFacingFor = map[byte]legacyFacing{
+		// stairs
+		53: stairsFacing, 67: stairsFacing, 108: stairsFacing, 109: stairsFacing, 114: stairsFacing,
+		128: stairsFacing, 134: stairsFacing, 135: stairsFacing, 136: stairsFacing, 156: stairsFacing,
+		163: stairsFacing, 164: stairsFacing, 180: stairsFacing, 203: stairsFacing,
+		// wall signs, ladders, furnaces, chests, dispensers, droppers, hoppers and wall banners
+		23: wallFacing, 54: wallFacing, 61: wallFacing, 62: wallFacing, 65: wallFacing, 68: wallFacing,
+		130: wallFacing, 146: wallFacing, 154: wallFacing, 158: wallFacing, 177: wallFacing,
+		// torches
+		50: torchFacing, 75: torchFacing, 76: torchFacing,
+		// beds, pumpkins, jack o'lanterns and fence gates
+		26: horizontalSWNE, 86: horizontalSWNE, 91: horizontalSWNE, 107: horizontalSWNE,
+		183: horizontalSWNE, 184: horizontalSWNE, 185: horizontalSWNE, 186: horizontalSWNE, 187: horizontalSWNE,
+		// repeaters and comparators
+		93: redstoneFacing, 94: redstoneFacing, 149: redstoneFacing, 150: redstoneFacing,
+	}
+	legacyDoors         = map[byte]bool{64: true, 71: true, 193: true, 194: true, 195: true, 196: true, 197: true}
+	legacyRotation16    = map[byte]bool{63: true, 176: true} // standing signs and banners
+	legacyAxisBlocks    = map[byte]bool{17: true, 162: true, 170: true}
+	legacyQuartzBlock   = byte(155)
+	legacyVines         = byte(106)
+	legacyVineDirection = [4]byte{4, 8, 1, 2} // north, east, south, west
+)
+
+// reorientLegacyData remaps the data value of a pre-1.13 block that depends on the direction it faces.
+func reorientLegacyData(block, data byte, o Orientation) byte {
+	if facing, ok := legacyFacingFor[block]; ok {
+		return facing.reorient(data, o)
+	}
+
+	switch {
+	case legacyDoors[block]:
+		if data&8 == 0 {
+			return doorFacing.reorient(data, o)
+		}
+		// The upper half only stores which side the hinge is on, which swaps when mirrored.
+		if o.isMirror() {
+			return data ^ 1
+		}
+		return data
+	case legacyRotation16[block]:
+		return byte(o.transformRotation16(int(data)))
+	case legacyAxisBlocks[block]:
+		// Logs and hay bales store their axis in bits 2 and 3: 4 is along X and 8 along Z.
+		if o.swapsAxes() && (data&0x0c == 4 || data&0x0c == 8) {
+			return data ^ 0x0c
+		}
+		return data
+	case block == legacyQuartzBlock:
+		// Pillar quartz is 3 along X and 4 along Z.
+		if o.swapsAxes() && (data == 3 || data == 4) {
+			return 7 - data
+		}
+		return data
+	case block == legacyVines:
+		var result byte
+		for d, bit := range legacyVineDirection {
+			if data&bit != 0 {
+				result |= legacyVineDirection[o.transformDirection(direction(d))]
+			}
+		}
+		return result
+	default:
+		return data
+	}
+}
+
+// reorientTileEntity updates facing that is stored in a tile entity rather than in the block data.
+func reorientTileEntity(tileEntity NBTCompound, o Orientation) {
+	if rot, ok := tileEntity.Int("Rot"); ok {
+		// Skulls placed on the floor.
+		tileEntity.SetInt("Rot", o.transformRotation16(rot))
+	}
+}
+
+// hangingFacing is the order of the Facing tag of paintings and item frames before 1.13: south, west, north, east.
+var hangingFacing = [4]direction{south, west, north, east}
+
+func reorientHangingEntity(entity NBTCompound, o Orientation) {
+	facing, ok := entity.Int("Facing")
+	if !ok || facing < 0 || facing > 3 {
+		return
+	}
+	transformed := o.transformDirection(hangingFacing[facing])
+	for i, d := range hangingFacing {
+		if d == transformed {
+			entity.SetInt("Facing", i)
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func writeTestSlime(t *testing.T, world *AnvilWorld) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := world.WriteAsSlime(context.Background(), &buf, SlimeWriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReorient_roundTrip(t *testing.T) {
+	original := writeTestSlime(t, newTestWorld())
+	for _, sequence := range [][]Orientation{
+		{OrientationRotate90, OrientationRotate90, OrientationRotate90, OrientationRotate90},
+		{OrientationRotate90, OrientationRotate270},
+		{OrientationRotate180, OrientationRotate180},
+		{OrientationMirrorX, OrientationMirrorX},
+		{OrientationMirrorZ, OrientationMirrorZ},
+		{OrientationMirrorX, OrientationMirrorZ, OrientationRotate180},
+	} {
+		world := newTestWorld()
+		for _, o := range sequence {
+			world.Reorient(o)
+		}
+		if !bytes.Equal(original, writeTestSlime(t, world)) {
+			t.Errorf("applying %v did not give back the original world", sequence)
+		}
+	}
+}
+
+func TestReorient_blocksAndEntities(t *testing.T) {
+	chunk := newTestChunk(0, 0)
+	section := &chunk.Sections[0]
+	// East-facing stairs at x=1, y=1, z=2.
+	idx := 1<<8 | 2<<4 | 1
+	section.Blocks[idx] = 53
+	setNibble(section.Data, idx, 0)
+	chunk.Entities[0]["Rotation"] = []interface{}{float32(0), float32(0)}
+	chunk.BiomeCells = make([]byte, biomeCellsPerChunk)
+	chunk.BiomeCells[biomeCellIndex(0, 5, 1)] = 7
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{X: 0, Z: 0}: chunk}}
+
+	world.Reorient(OrientationRotate90)
+
+	rotated, ok := world.chunks[ChunkCoord{X: -1, Z: 0}]
+	if !ok {
+		t.Fatalf("chunk 0,0 was not moved to -1,0: %v", world.getChunkKeys())
+	}
+	// Block 1,2 maps to -3,1, which is local 13,1 in chunk -1,0.
+	rotatedIdx := 1<<8 | 1<<4 | 13
+	if block := rotated.Sections[0].Blocks[rotatedIdx]; block != 53 {
+		t.Fatalf("expected stairs at the rotated position, found block %d", block)
+	}
+	if data := getNibble(rotated.Sections[0].Data, rotatedIdx); data != 2 {
+		t.Errorf("expected south-facing stairs (2), got %d", data)
+	}
+
+	// Cell 0,1 covers columns 0-3,4-7, which map to local 8-11,0-3.
+	if biome := rotated.BiomeCells[biomeCellIndex(2, 5, 0)]; biome != 7 {
+		t.Errorf("expected the biome cell at the rotated position, found biome %d", biome)
+	}
+
+	pos := rotated.Entities[0]["Pos"].([]interface{})
+	if pos[0] != float64(-0.5) || pos[2] != float64(0.5) {
+		t.Errorf("entity was moved to %v", pos)
+	}
+	if yaw := rotated.Entities[0]["Rotation"].([]interface{})[0]; yaw != float32(90) {
+		t.Errorf("entity yaw is %v, want 90", yaw)
+	}
+	if x, z := rotated.TileEntities[0]["x"], rotated.TileEntities[0]["z"]; x != int32(-1) || z != int32(0) {
+		t.Errorf("tile entity was moved to %v,%v", x, z)
+	}
+}
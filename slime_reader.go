@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/astei/anvil2slime/nbt"
+	"github.com/klauspost/compress/zstd"
+)
+
+var ErrNotSlime = errors.New("slime: not a Slime world")
+var ErrUnsupportedSlimeVersion = errors.New("slime: unsupported version")
+var ErrCorruptSlime = errors.New("slime: corrupt world")
+
+// ReadSlimeWorld reads a world written in the Slime format, version 1 through 5.
+func ReadSlimeWorld(ctx context.Context, reader io.Reader) (*AnvilWorld, error) {
+	zstdReader, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer zstdReader.Close()
+
+	r := &slimeReader{ctx: ctx, reader: bufio.NewReader(reader), zstdReader: zstdReader, orphans: make(RemovedIDs)}
+	world, err := r.readWorld()
+	for _, kind := range r.orphans.IDs() {
+		logger.Warn("dropped data outside the stored chunks", "kind", kind, "count", r.orphans[kind])
+	}
+	return world, err
+}
+
+type slimeReader struct {
+	ctx        context.Context
+	reader     io.Reader
+	zstdReader *zstd.Decoder
+	version    uint8
+	flattened  bool
+	// orphans counts, by kind, the tile entities, entities and ticks that were dropped because the chunk they are in
+	// is not stored. Other tools write them, so they do not make a world unreadable.
+	orphans RemovedIDs
+}
+
+func (r *slimeReader) readWorld() (*AnvilWorld, error) {
+	var magic struct {
+		Magic   uint16
+		Version uint8
+	}
+	if err := binary.Read(r.reader, binary.BigEndian, &magic); err != nil {
+		return nil, err
+	}
+	if magic.Magic != slimeHeader {
+		return nil, ErrNotSlime
+	}
+	if magic.Version < 1 || magic.Version > slimeLatestVersion {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedSlimeVersion, magic.Version)
+	}
+	r.version = magic.Version
+	if r.version >= 4 {
+		var v113 [1]byte
+		if _, err := io.ReadFull(r.reader, v113[:]); err != nil {
+			return nil, err
+		}
+		r.flattened = v113[0] != 0
+	}
+
+	var header struct {
+		MinX  int16
+		MinZ  int16
+		Width uint16
+		Depth uint16
+	}
+	if err := binary.Read(r.reader, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	width, depth := int(header.Width), int(header.Depth)
+	if width == 0 || depth == 0 {
+		return nil, fmt.Errorf("%w: the world is %dx%d chunks", ErrCorruptSlime, width, depth)
+	}
+
+	populated := make([]byte, int(math.Ceil(float64(width*depth)/8)))
+	if _, err := io.ReadFull(r.reader, populated); err != nil {
+		return nil, err
+	}
+
+	chunkData, err := r.readZstdCompressed()
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := r.readChunks(bytes.NewReader(chunkData), int(header.MinX), int(header.MinZ), width, depth, populated)
+	if err != nil {
+		return nil, err
+	}
+	world := &AnvilWorld{chunks: chunks}
+
+	var tiles struct {
+		Tiles []NBTCompound `nbt:"tiles"`
+	}
+	if err = r.readCompressedNbt(&tiles); err != nil {
+		return nil, fmt.Errorf("could not read tile entities: %w", err)
+	}
+	if err = r.addAtBlockPositions(world, tiles.Tiles, "tile entity", NBTCompound.ID,
+		func(chunk *MinecraftChunk, tileEntity NBTCompound) {
+			chunk.TileEntities = append(chunk.TileEntities, tileEntity)
+		}); err != nil {
+		return nil, err
+	}
+
+	if r.version >= 3 {
+		var hasEntities [1]byte
+		if _, err = io.ReadFull(r.reader, hasEntities[:]); err != nil {
+			return nil, err
+		}
+		if hasEntities[0] != 0 {
+			var entities struct {
+				Entities []NBTCompound `nbt:"entities"`
+			}
+			if err = r.readCompressedNbt(&entities); err != nil {
+				return nil, fmt.Errorf("could not read entities: %w", err)
+			}
+			for _, entity := range entities.Entities {
+				owner, ok := entityChunk(entity)
+				if !ok {
+					return nil, fmt.Errorf("%w: entity %s has no position", ErrCorruptSlime, entity.ID())
+				}
+				r.addToChunk(world, owner.X, owner.Z, "entity", func(chunk *MinecraftChunk) {
+					chunk.Entities = append(chunk.Entities, entity)
+				})
+			}
+		}
+	}
+
+	if r.version >= 2 {
+		// Only the scheduled ticks and biome names that anvil2slime writes are read from the extra compound.
+		var extra struct {
+			TileTicks   []NBTCompound
+			LiquidTicks []NBTCompound
+			Biomes      []slimeChunkBiomes
+		}
+		if err = r.readCompressedNbt(&extra); err != nil {
+			return nil, fmt.Errorf("could not read extra data: %w", err)
+		}
+		if err = r.addAtBlockPositions(world, extra.TileTicks, "block tick", tickTarget,
+			func(chunk *MinecraftChunk, tick NBTCompound) {
+				chunk.TileTicks = append(chunk.TileTicks, tick)
+			}); err != nil {
+			return nil, err
+		}
+		if err = r.addAtBlockPositions(world, extra.LiquidTicks, "fluid tick", tickTarget,
+			func(chunk *MinecraftChunk, tick NBTCompound) {
+				chunk.LiquidTicks = append(chunk.LiquidTicks, tick)
+			}); err != nil {
+			return nil, err
+		}
+		for _, biomes := range extra.Biomes {
+			biomes := biomes
+			r.addToChunk(world, int(biomes.X), int(biomes.Z), "biomes", func(chunk *MinecraftChunk) {
+				chunk.setExtraBiomes(biomes.Custom, biomes.Cells, biomes.CustomCells)
+			})
+		}
+	}
+	return world, nil
+}
+
+// slimeChunkBiomes holds the biomes of a chunk that its header cannot store, as writeExtra writes them.
+type slimeChunkBiomes struct {
+	X           int32 `nbt:"x"`
+	Z           int32 `nbt:"z"`
+	Custom      []string
+	Cells       []int32
+	CustomCells []string
+}
+
+// addAtBlockPositions adds compounds that hold their block position in x, y and z tags, such as tile entities, to the
+// chunk they are in. kind and name describe a compound that has no position.
+func (r *slimeReader) addAtBlockPositions(world *AnvilWorld, compounds []NBTCompound, kind string,
+	name func(NBTCompound) string, add func(chunk *MinecraftChunk, compound NBTCompound)) error {
+	for _, compound := range compounds {
+		x, okX := compound.Int("x")
+		z, okZ := compound.Int("z")
+		if !okX || !okZ {
+			return fmt.Errorf("%w: %s %s has no position", ErrCorruptSlime, kind, name(compound))
+		}
+		r.addToChunk(world, x>>4, z>>4, kind, func(chunk *MinecraftChunk) {
+			add(chunk, compound)
+		})
+	}
+	return nil
+}
+
+// addToChunk adds data of the given kind to a chunk, or counts it as an orphan if the chunk is not stored.
+func (r *slimeReader) addToChunk(world *AnvilWorld, chunkX, chunkZ int, kind string, add func(chunk *MinecraftChunk)) {
+	coord := ChunkCoord{X: chunkX, Z: chunkZ}
+	chunk, ok := world.chunks[coord]
+	if !ok {
+		r.orphans[kind]++
+		return
+	}
+	add(&chunk)
+	world.chunks[coord] = chunk
+}
+
+func (r *slimeReader) readChunks(data io.Reader, minX, minZ, width, depth int, populated []byte) (map[ChunkCoord]MinecraftChunk, error) {
+	chunks := make(map[ChunkCoord]MinecraftChunk)
+	for z := 0; z < depth; z++ {
+		for x := 0; x < width; x++ {
+			idx := z*width + x
+			if populated[idx/8]&(1<<(idx%8)) == 0 {
+				continue
+			}
+			if err := r.ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			// Slime files only hold finished chunks, and do not keep how long players spent in them.
+			chunk := MinecraftChunk{X: minX + x, Z: minZ + z, InhabitedTime: unknownInhabitedTime, Status: chunkStatusFull,
+				TerrainPopulated: 1}
+			if err := r.readChunk(data, &chunk); err != nil {
+				return nil, fmt.Errorf("could not read chunk %d,%d: %w", chunk.X, chunk.Z, err)
+			}
+			chunks[ChunkCoord{X: chunk.X, Z: chunk.Z}] = chunk
+		}
+	}
+	return chunks, nil
+}
+
+func (r *slimeReader) readChunk(data io.Reader, chunk *MinecraftChunk) error {
+	if r.flattened {
+		if err := r.readFlattenedChunkHeader(data, chunk); err != nil {
+			return err
+		}
+		return r.readChunkSections(data, chunk)
+	}
+
+	heightMap := make([]int32, 256)
+	if err := binary.Read(data, binary.BigEndian, heightMap); err != nil {
+		return err
+	}
+	chunk.HeightMap = make([]int, len(heightMap))
+	for i, height := range heightMap {
+		chunk.HeightMap[i] = int(height)
+	}
+
+	chunk.Biomes = make([]byte, 256)
+	if _, err := io.ReadFull(data, chunk.Biomes); err != nil {
+		return err
+	}
+	return r.readChunkSections(data, chunk)
+}
+
+func (r *slimeReader) readFlattenedChunkHeader(data io.Reader, chunk *MinecraftChunk) error {
+	var heightMapsLength int32
+	if err := binary.Read(data, binary.BigEndian, &heightMapsLength); err != nil {
+		return err
+	}
+	if heightMapsLength < 0 {
+		return fmt.Errorf("%w: negative height map length", ErrCorruptSlime)
+	}
+	if heightMapsLength > 0 {
+		heightMaps := make([]byte, heightMapsLength)
+		if _, err := io.ReadFull(data, heightMaps); err != nil {
+			return err
+		}
+		var compound NBTCompound
+		if err := nbt.Unmarshal(heightMaps, &compound); err != nil {
+			return fmt.Errorf("could not read height maps: %w", err)
+		}
+		// Height maps are packed with padding from 1.16 on, and only then can their length not be a multiple of 4.
+		padded := false
+		for _, value := range compound {
+			if packed, ok := value.([]int64); ok && len(packed)%4 != 0 {
+				padded = true
+			}
+		}
+		chunk.HeightMaps = decodeHeightMaps(compound, padded, 0)
+	}
+
+	biomes := make([]int32, 256)
+	if err := binary.Read(data, binary.BigEndian, biomes); err != nil {
+		return err
+	}
+	chunk.Biomes = make([]byte, len(biomes))
+	for i, biome := range biomes {
+		chunk.Biomes[i] = byte(biome)
+	}
+	return nil
+}
+
+func (r *slimeReader) readChunkSections(data io.Reader, chunk *MinecraftChunk) error {
+	var sectionsPopulated [2]byte
+	if _, err := io.ReadFull(data, sectionsPopulated[:]); err != nil {
+		return err
+	}
+	// Chunks are only stored once they are lit, unless the light was left out.
+	chunk.LightPopulated = 1
+	for y := 0; y < 16; y++ {
+		if sectionsPopulated[y/8]&(1<<(y%8)) == 0 {
+			continue
+		}
+		section := MinecraftChunkSection{Y: uint8(y)}
+		var err error
+		if section.BlockLight, err = r.readLight(data); err != nil {
+			return err
+		}
+		if r.flattened {
+			err = r.readPalette(data, &section)
+		} else {
+			section.Blocks, section.Data = make([]byte, 4096), make([]byte, 2048)
+			if _, err = io.ReadFull(data, section.Blocks); err == nil {
+				_, err = io.ReadFull(data, section.Data)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if section.SkyLight, err = r.readLight(data); err != nil {
+			return err
+		}
+		if section.BlockLight == nil || section.SkyLight == nil {
+			chunk.LightPopulated = 0
+		}
+
+		if r.version < 4 {
+			var hypixelBlocksLength uint16
+			if err = binary.Read(data, binary.BigEndian, &hypixelBlocksLength); err != nil {
+				return err
+			}
+			if _, err = io.CopyN(ioutil.Discard, data, int64(hypixelBlocksLength)); err != nil {
+				return err
+			}
+		}
+		chunk.Sections = append(chunk.Sections, section)
+	}
+	return nil
+}
+
+func (r *slimeReader) readPalette(data io.Reader, section *MinecraftChunkSection) error {
+	var paletteLength int32
+	if err := binary.Read(data, binary.BigEndian, &paletteLength); err != nil {
+		return err
+	}
+	if paletteLength <= 0 || paletteLength > 4096 {
+		return fmt.Errorf("%w: palette of %d entries", ErrCorruptSlime, paletteLength)
+	}
+	section.Palette = make([]NBTCompound, paletteLength)
+	for i := range section.Palette {
+		var entryLength int32
+		if err := binary.Read(data, binary.BigEndian, &entryLength); err != nil {
+			return err
+		}
+		if entryLength < 0 {
+			return fmt.Errorf("%w: negative palette entry length", ErrCorruptSlime)
+		}
+		entry := make([]byte, entryLength)
+		if _, err := io.ReadFull(data, entry); err != nil {
+			return err
+		}
+		if err := nbt.Unmarshal(entry, &section.Palette[i]); err != nil {
+			return err
+		}
+	}
+
+	var blockStatesLength int32
+	if err := binary.Read(data, binary.BigEndian, &blockStatesLength); err != nil {
+		return err
+	}
+	if blockStatesLength%64 != 0 || blockStatesLength < 4*64 || blockStatesLength > 12*64 {
+		return fmt.Errorf("%w: %d longs of block states", ErrCorruptSlime, blockStatesLength)
+	}
+	section.BlockStates = make([]int64, blockStatesLength)
+	return binary.Read(data, binary.BigEndian, section.BlockStates)
+}
+
+// readLight reads a light array, returning nil if the world left it out.
+func (r *slimeReader) readLight(data io.Reader) ([]byte, error) {
+	if r.version >= 5 {
+		var present bool
+		if err := binary.Read(data, binary.BigEndian, &present); err != nil || !present {
+			return nil, err
+		}
+	}
+	light := make([]byte, 2048)
+	if _, err := io.ReadFull(data, light); err != nil {
+		return nil, err
+	}
+	return light, nil
+}
+
+func (r *slimeReader) readZstdCompressed() ([]byte, error) {
+	var lengths struct {
+		Compressed   uint32
+		Uncompressed uint32
+	}
+	if err := binary.Read(r.reader, binary.BigEndian, &lengths); err != nil {
+		return nil, err
+	}
+	compressed := make([]byte, lengths.Compressed)
+	if _, err := io.ReadFull(r.reader, compressed); err != nil {
+		return nil, err
+	}
+	uncompressed, err := r.zstdReader.DecodeAll(compressed, make([]byte, 0, lengths.Uncompressed))
+	if err != nil {
+		return nil, err
+	}
+	if len(uncompressed) != int(lengths.Uncompressed) {
+		return nil, fmt.Errorf("%w: expected %d bytes after decompression, got %d", ErrCorruptSlime,
+			lengths.Uncompressed, len(uncompressed))
+	}
+	return uncompressed, nil
+}
+
+func (r *slimeReader) readCompressedNbt(v interface{}) error {
+	data, err := r.readZstdCompressed()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return nbt.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestReadSlimeWorld_roundTrip(t *testing.T) {
+	original := newTestWorld()
+	written := writeTestSlime(t, original)
+
+	read, err := ReadSlimeWorld(context.Background(), bytes.NewReader(written))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.chunks) != len(original.chunks) {
+		t.Fatalf("read %d chunks, want %d", len(read.chunks), len(original.chunks))
+	}
+	for coord, chunk := range original.chunks {
+		readChunk := read.chunks[coord]
+		if !reflect.DeepEqual(chunk.Sections, readChunk.Sections) {
+			t.Errorf("sections of chunk %v differ", coord)
+		}
+		if len(readChunk.Entities) != 1 || len(readChunk.TileEntities) != 1 {
+			t.Errorf("chunk %v has %d entities and %d tile entities, want 1 of each", coord,
+				len(readChunk.Entities), len(readChunk.TileEntities))
+		}
+	}
+
+	if rewritten := writeTestSlime(t, read); !bytes.Equal(written, rewritten) {
+		t.Error("writing the world that was read back produced different bytes")
+	}
+}
+
+func TestReadSlimeWorld_light(t *testing.T) {
+	unlit := ChunkCoord{X: 1, Z: 1}
+	for version := uint8(slimeOldestWritableVersion); version <= slimeLatestVersion; version++ {
+		world := newTestWorld()
+		for coord, chunk := range world.chunks {
+			chunk.LightPopulated = 1
+			if coord == unlit {
+				chunk.LightPopulated = 0
+			}
+			for _, section := range chunk.Sections {
+				for i := range section.SkyLight {
+					section.SkyLight[i] = 0xff
+				}
+			}
+			world.chunks[coord] = chunk
+		}
+
+		var buf bytes.Buffer
+		options := SlimeWriteOptions{Version: version, Light: LightStripUnpopulated}
+		if err := world.WriteAsSlime(context.Background(), &buf, options); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadSlimeWorld(context.Background(), &buf)
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+
+		for coord, chunk := range read.chunks {
+			skyLight := chunk.Sections[0].SkyLight
+			switch {
+			case coord != unlit:
+				if !bytes.Equal(skyLight, world.chunks[coord].Sections[0].SkyLight) {
+					t.Errorf("version %d: light of chunk %v was not kept", version, coord)
+				}
+			case version >= 5:
+				if skyLight != nil || chunk.LightPopulated != 0 {
+					t.Errorf("version %d: light of unlit chunk was not left out", version)
+				}
+			default:
+				if !bytes.Equal(skyLight, noLight[:]) {
+					t.Errorf("version %d: light of unlit chunk was not zeroed", version)
+				}
+			}
+		}
+	}
+}
+
+func TestReadSlimeWorld_orphans(t *testing.T) {
+	world := newTestWorld()
+	chunk := world.chunks[ChunkCoord{}]
+	chunk.Entities[0]["Pos"] = []interface{}{float64(500), float64(1), float64(0.5)}
+	chunk.TileEntities = append(chunk.TileEntities, NBTCompound{"id": "minecraft:chest", "x": int32(-500),
+		"y": int32(1), "z": int32(0)})
+	chunk.TileTicks = []NBTCompound{newTestTick("minecraft:repeater", 0, 1, 500)}
+	world.chunks[ChunkCoord{}] = chunk
+
+	read, err := ReadSlimeWorld(context.Background(), bytes.NewReader(writeTestSlime(t, world)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readChunk := read.chunks[ChunkCoord{}]
+	if len(readChunk.Entities) != 0 || len(readChunk.TileEntities) != 1 || len(readChunk.TileTicks) != 0 {
+		t.Errorf("expected data outside the stored chunks to be dropped, got %d entities, %d tile entities and %d "+
+			"ticks", len(readChunk.Entities), len(readChunk.TileEntities), len(readChunk.TileTicks))
+	}
+}
@@ -2,37 +2,154 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/astei/anvil2slime/nbt"
 	"github.com/klauspost/compress/zstd"
 	"io"
 	"io/ioutil"
+	"math"
 	"sort"
 )
 
 const slimeHeader = 0xB10B
-const slimeLatestVersion = 3
 
-func slimeChunkKey(coord ChunkCoord) int64 {
-	return (int64(coord.Z) * 0x7fffffff) + int64(coord.X)
+// Slime versions 3 through 5 can be written. Version 4 added a flag for 1.13 worlds and version 5 made light
+// optional. Most loaders expect version 3, so that is the default.
+const (
+	slimeOldestWritableVersion = 3
+	slimeDefaultVersion        = 3
+	slimeLatestVersion         = 5
+)
+
+// Slime stores the lowest chunk coordinates as shorts and the width and depth of the world as shorts that loaders
+// read back as signed values.
+const (
+	slimeMinChunkCoord = math.MinInt16
+	slimeMaxChunkCoord = math.MaxInt16
+	slimeMaxWorldSize  = math.MaxInt16
+)
+
+// Tags of the extra compound that hold the scheduled ticks of the world and the biomes its chunks cannot store.
+const (
+	slimeExtraTileTicks   = "TileTicks"
+	slimeExtraLiquidTicks = "LiquidTicks"
+	slimeExtraBiomes      = "Biomes"
+)
+
+var ErrEmptyWorld = errors.New("world has no chunks")
+var ErrFlattenedWorld = errors.New("world uses 1.13 block states")
+var ErrSlimeOutOfBounds = errors.New("world does not fit in a Slime file")
+
+// SlimeWriteOptions controls how WriteAsSlime writes a world. The zero value uses the default settings.
+type SlimeWriteOptions struct {
+	// CompressionLevel is the zstd level every compressed part of the file is written with. Defaults to
+	// zstd.SpeedDefault.
+	CompressionLevel zstd.EncoderLevel
+	// CompressionWindowSize is the zstd window size in bytes, which must be a power of two. Zero uses the default
+	// window size for the compression level.
+	CompressionWindowSize int
+	// CompressionConcurrency is how many goroutines the encoder may use. Defaults to 1.
+	CompressionConcurrency int
+	// Version is the Slime version to write. Zero uses the default version, or the latest one for worlds that use
+	// 1.13 block states.
+	Version uint8
+	// Light decides which light to leave out. Versions before 5 always store light, so left out light is written as
+	// zeroes instead, which still compresses to almost nothing.
+	Light LightMode
 }
 
-func (world *AnvilWorld) WriteAsSlime(writer io.Writer) error {
-	zstdWriter, err := zstd.NewWriter(ioutil.Discard)
+// WriteAsSlime writes the world to writer in the Slime format. If ctx is cancelled, writing stops and the context's
+// error is returned; whatever was already written to writer should be discarded.
+func (world *AnvilWorld) WriteAsSlime(ctx context.Context, writer io.Writer, options SlimeWriteOptions) error {
+	zstdWriter, err := zstd.NewWriter(ioutil.Discard, options.encoderOptions()...)
 	if err != nil {
 		return err
 	}
-	slimeWriter := &slimeWriter{writer: writer, world: world, zstdWriter: zstdWriter}
+	defer zstdWriter.Close()
+	slimeWriter := &slimeWriter{
+		ctx:        ctx,
+		writer:     writer,
+		world:      world,
+		zstdWriter: zstdWriter,
+		version:    options.version(world.IsFlattened()),
+		light:      options.Light,
+	}
 	return slimeWriter.writeWorld()
 }
 
+// Validate reports whether the options are usable, so that mistakes can be reported before a world is loaded.
+func (o SlimeWriteOptions) Validate() error {
+	if version := o.version(false); version < slimeOldestWritableVersion || version > slimeLatestVersion {
+		return fmt.Errorf("%w %d (expected %d to %d)", ErrUnsupportedSlimeVersion, version,
+			slimeOldestWritableVersion, slimeLatestVersion)
+	}
+	encoder, err := zstd.NewWriter(ioutil.Discard, o.encoderOptions()...)
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func (o SlimeWriteOptions) version(flattened bool) uint8 {
+	switch {
+	case o.Version != 0:
+		return o.Version
+	case flattened:
+		return slimeLatestVersion
+	default:
+		return slimeDefaultVersion
+	}
+}
+
+// encoderOptions pins down every encoder setting that affects the compressed output, so the same world and options
+// always produce the same bytes regardless of the machine the conversion runs on.
+func (o SlimeWriteOptions) encoderOptions() []zstd.EOption {
+	level := o.CompressionLevel
+	if level == 0 {
+		level = zstd.SpeedDefault
+	}
+	concurrency := o.CompressionConcurrency
+	if concurrency == 0 {
+		concurrency = 1
+	}
+
+	var options []zstd.EOption
+	if o.CompressionWindowSize != 0 {
+		options = append(options, zstd.WithWindowSize(o.CompressionWindowSize))
+	}
+	return append(options,
+		zstd.WithEncoderLevel(level),
+		zstd.WithEncoderConcurrency(concurrency),
+		zstd.WithEncoderCRC(true),
+	)
+}
+
 type slimeWriter struct {
+	ctx        context.Context
 	writer     io.Writer
 	world      *AnvilWorld
 	zstdWriter *zstd.Encoder
+	version    uint8
+	light      LightMode
+	flattened  bool
 }
 
 func (w *slimeWriter) writeWorld() (err error) {
+	if err = w.world.ValidateSlimeBounds(); err != nil {
+		return
+	}
+	if w.flattened = w.world.IsFlattened(); w.flattened {
+		if w.version < 4 {
+			return fmt.Errorf("%w, which Slime version %d cannot store (use version 4 or later)", ErrFlattenedWorld,
+				w.version)
+		}
+		if err = w.world.checkAllSectionsFlattened(); err != nil {
+			return
+		}
+	}
 	if err = w.writeHeader(); err != nil {
 		return
 	}
@@ -56,22 +173,30 @@ func (w *slimeWriter) writeHeader() (err error) {
 	minChunkXZ, width, depth := w.determineChunkBounds()
 	used := w.createChunkBitset(width, depth, minChunkXZ)
 
-	var header struct {
-		Magic   uint16
-		Version uint8
-		MinX    int16
-		MinZ    int16
-		Width   uint16
-		Depth   uint16
-	}
-	header.Magic = slimeHeader
-	header.Version = slimeLatestVersion
-	header.MinX = int16(minChunkXZ.X)
-	header.MinZ = int16(minChunkXZ.Z)
-	header.Width = uint16(width)
-	header.Depth = uint16(depth)
-
-	if err = binary.Write(w.writer, binary.BigEndian, header); err != nil {
+	if err = binary.Write(w.writer, binary.BigEndian, uint16(slimeHeader)); err != nil {
+		return
+	}
+	if _, err = w.writer.Write([]byte{w.version}); err != nil {
+		return
+	}
+	if w.version >= 4 {
+		if err = binary.Write(w.writer, binary.BigEndian, w.flattened); err != nil {
+			return
+		}
+	}
+
+	var bounds struct {
+		MinX  int16
+		MinZ  int16
+		Width uint16
+		Depth uint16
+	}
+	bounds.MinX = int16(minChunkXZ.X)
+	bounds.MinZ = int16(minChunkXZ.Z)
+	bounds.Width = uint16(width)
+	bounds.Depth = uint16(depth)
+
+	if err = binary.Write(w.writer, binary.BigEndian, bounds); err != nil {
 		return
 	}
 	_, err = w.writer.Write(used)
@@ -92,30 +217,70 @@ func (w *slimeWriter) createChunkBitset(width int, depth int, minChunkXZ ChunkCo
 }
 
 func (w *slimeWriter) determineChunkBounds() (minChunkXZ ChunkCoord, width int, depth int) {
+	return w.world.determineChunkBounds()
+}
+
+func (world *AnvilWorld) determineChunkBounds() (minChunkXZ ChunkCoord, width int, depth int) {
 	// Slime uses its own order for chunks, but still requires us to determine the maximum/minimum XZ coordinates.
-	minX, maxX, minZ, maxZ := w.world.getMinXZ()
+	minX, maxX, minZ, maxZ := world.getMinXZ()
 
 	width = maxX - minX + 1
 	depth = maxZ - minZ + 1
 	return ChunkCoord{X: minX, Z: minZ}, width, depth
 }
 
+// checkAllSectionsFlattened makes sure the world does not mix legacy and flattened sections, which a Slime file cannot
+// store together.
+func (world *AnvilWorld) checkAllSectionsFlattened() error {
+	for _, coord := range world.getSlimeSortedChunkKeys() {
+		for _, section := range world.chunks[coord].Sections {
+			if !section.usesPalette() {
+				return fmt.Errorf("%w, but chunk %d,%d still has legacy blocks in section %d", ErrFlattenedWorld,
+					coord.X, coord.Z, section.Y)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateSlimeBounds checks that the world's chunk coordinates can be stored in a Slime header. Worlds that are too
+// far from the origin can be moved with Recenter; worlds that are too wide or deep cannot be stored at all.
+func (world *AnvilWorld) ValidateSlimeBounds() error {
+	if len(world.chunks) == 0 {
+		return ErrEmptyWorld
+	}
+
+	minChunkXZ, width, depth := world.determineChunkBounds()
+	if width > slimeMaxWorldSize || depth > slimeMaxWorldSize {
+		return fmt.Errorf("%w: the world is %dx%d chunks but Slime allows at most %dx%d",
+			ErrSlimeOutOfBounds, width, depth, slimeMaxWorldSize, slimeMaxWorldSize)
+	}
+	if minChunkXZ.X < slimeMinChunkCoord || minChunkXZ.X > slimeMaxChunkCoord {
+		return fmt.Errorf("%w: the lowest chunk X coordinate is %d but must be between %d and %d",
+			ErrSlimeOutOfBounds, minChunkXZ.X, slimeMinChunkCoord, slimeMaxChunkCoord)
+	}
+	if minChunkXZ.Z < slimeMinChunkCoord || minChunkXZ.Z > slimeMaxChunkCoord {
+		return fmt.Errorf("%w: the lowest chunk Z coordinate is %d but must be between %d and %d",
+			ErrSlimeOutOfBounds, minChunkXZ.Z, slimeMinChunkCoord, slimeMaxChunkCoord)
+	}
+	return nil
+}
+
 func (w *slimeWriter) writeChunks() (err error) {
-	slimeSorted := w.world.getChunkKeys()
-	sort.Slice(slimeSorted, func(one, two int) bool {
-		k1 := slimeChunkKey(slimeSorted[one])
-		k2 := slimeChunkKey(slimeSorted[two])
-		return k1 < k2
-	})
+	slimeSorted := w.world.getSlimeSortedChunkKeys()
 
 	var out bytes.Buffer
 	for _, coord := range slimeSorted {
+		if err = w.ctx.Err(); err != nil {
+			return
+		}
 		chunk := w.world.chunks[coord]
 		if err = w.writeChunkHeader(chunk, &out); err != nil {
 			return
 		}
+		stripLight := w.light.strips(chunk)
 		for _, section := range chunk.Sections {
-			if err = w.writeChunkSection(section, &out); err != nil {
+			if err = w.writeChunkSection(section, stripLight, &out); err != nil {
 				return
 			}
 		}
@@ -125,7 +290,10 @@ func (w *slimeWriter) writeChunks() (err error) {
 }
 
 func (w *slimeWriter) writeChunkHeader(chunk MinecraftChunk, out io.Writer) (err error) {
-	for _, heightEntry := range chunk.HeightMap {
+	if w.flattened {
+		return w.writeFlattenedChunkHeader(chunk, out)
+	}
+	for _, heightEntry := range chunk.legacyHeightMap() {
 		if err = binary.Write(out, binary.BigEndian, int32(heightEntry)); err != nil {
 			return
 		}
@@ -137,6 +305,32 @@ func (w *slimeWriter) writeChunkHeader(chunk MinecraftChunk, out io.Writer) (err
 	return
 }
 
+// writeFlattenedChunkHeader writes the 1.13 layout, which stores height maps as a compound and biomes as ints.
+// Chunks without named height maps are written without any, and the server calculates them when it loads the chunk.
+func (w *slimeWriter) writeFlattenedChunkHeader(chunk MinecraftChunk, out io.Writer) (err error) {
+	var heightMaps bytes.Buffer
+	if len(chunk.HeightMaps) > 0 {
+		if err = nbt.Marshal(&heightMaps, encodeHeightMaps(chunk.HeightMaps)); err != nil {
+			return
+		}
+	}
+	if err = binary.Write(out, binary.BigEndian, int32(heightMaps.Len())); err != nil {
+		return
+	}
+	if _, err = out.Write(heightMaps.Bytes()); err != nil {
+		return
+	}
+	biomes := make([]int32, 256)
+	for i := 0; i < len(biomes) && i < len(chunk.Biomes); i++ {
+		biomes[i] = int32(chunk.Biomes[i])
+	}
+	if err = binary.Write(out, binary.BigEndian, biomes); err != nil {
+		return
+	}
+	w.writeChunkSectionsPopulatedBitmask(chunk, out)
+	return
+}
+
 func (w *slimeWriter) writeChunkSectionsPopulatedBitmask(chunk MinecraftChunk, out io.Writer) {
 	sectionsPopulated := newFixedBitSet(16)
 	for _, section := range chunk.Sections {
@@ -146,26 +340,71 @@ func (w *slimeWriter) writeChunkSectionsPopulatedBitmask(chunk MinecraftChunk, o
 	return
 }
 
-func (w *slimeWriter) writeChunkSection(section MinecraftChunkSection, out io.Writer) (err error) {
-	if _, err = out.Write(section.BlockLight); err != nil {
+func (w *slimeWriter) writeChunkSection(section MinecraftChunkSection, stripLight bool, out io.Writer) (err error) {
+	if err = w.writeLight(section.BlockLight, stripLight, out); err != nil {
 		return
 	}
-	if _, err = out.Write(section.Blocks); err != nil {
+	if w.flattened {
+		err = w.writePalette(section, out)
+	} else if _, err = out.Write(section.Blocks); err == nil {
+		_, err = out.Write(section.Data)
+	}
+	if err != nil {
 		return
 	}
-	if _, err = out.Write(section.Data); err != nil {
+	if err = w.writeLight(section.SkyLight, stripLight, out); err != nil {
 		return
 	}
-	if _, err = out.Write(section.SkyLight); err != nil {
+	if w.version < 4 {
+		// No "Hypixel blocks"; later versions dropped them entirely.
+		if err = binary.Write(out, binary.BigEndian, uint16(0)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (w *slimeWriter) writePalette(section MinecraftChunkSection, out io.Writer) (err error) {
+	if err = binary.Write(out, binary.BigEndian, int32(len(section.Palette))); err != nil {
 		return
 	}
-	if err = binary.Write(out, binary.BigEndian, uint16(0)); err != nil {
+	for _, entry := range section.Palette {
+		var buf bytes.Buffer
+		if err = nbt.NewEncoder(&buf).Encode(entry); err != nil {
+			return
+		}
+		if err = binary.Write(out, binary.BigEndian, int32(buf.Len())); err != nil {
+			return
+		}
+		if _, err = buf.WriteTo(out); err != nil {
+			return
+		}
+	}
+	if err = binary.Write(out, binary.BigEndian, int32(len(section.BlockStates))); err != nil {
 		return
 	}
+	return binary.Write(out, binary.BigEndian, section.BlockStates)
+}
+
+var noLight [2048]byte
+
+func (w *slimeWriter) writeLight(light []byte, strip bool, out io.Writer) (err error) {
+	if w.version >= 5 {
+		present := !strip && light != nil
+		if err = binary.Write(out, binary.BigEndian, present); err != nil || !present {
+			return
+		}
+	} else if strip || light == nil {
+		light = noLight[:]
+	}
+	_, err = out.Write(light)
 	return
 }
 
 func (w *slimeWriter) writeZstdCompressed(buf *bytes.Buffer) (err error) {
+	if err = w.ctx.Err(); err != nil {
+		return
+	}
 	uncompressedSize := buf.Len()
 
 	var compressedOutput bytes.Buffer
@@ -189,26 +428,26 @@ func (w *slimeWriter) writeZstdCompressed(buf *bytes.Buffer) (err error) {
 }
 
 func (w *slimeWriter) writeTileEntities() (err error) {
-	var tileEntities []interface{}
-	for _, chunk := range w.world.chunks {
-		tileEntities = append(tileEntities, chunk.TileEntities...)
+	var tileEntities []NBTCompound
+	for _, coord := range w.world.getSlimeSortedChunkKeys() {
+		tileEntities = append(tileEntities, w.world.chunks[coord].TileEntities...)
 	}
 
 	var compound struct {
-		Tiles []interface{} `nbt:"tiles"`
+		Tiles []NBTCompound `nbt:"tiles"`
 	}
 	compound.Tiles = tileEntities
 	return w.writeCompressedNbt(compound)
 }
 
 func (w *slimeWriter) writeEntities() (err error) {
-	var entities []interface{}
-	for _, chunk := range w.world.chunks {
-		entities = append(entities, chunk.Entities...)
+	var entities []NBTCompound
+	for _, coord := range w.world.getSlimeSortedChunkKeys() {
+		entities = append(entities, w.world.chunks[coord].Entities...)
 	}
 
 	var compound struct {
-		Entities []interface{} `nbt:"entities"`
+		Entities []NBTCompound `nbt:"entities"`
 	}
 	compound.Entities = entities
 
@@ -226,10 +465,32 @@ func (w *slimeWriter) writeCompressedNbt(compound interface{}) (err error) {
 	return w.writeZstdCompressed(&buf)
 }
 
+// writeExtra writes the extra compound, which holds the scheduled ticks of the world, the names of biomes without a
+// numeric id and the biomes of each 4x4x4 cell, since Slime versions 3 to 5 have no place for them. Like tile
+// entities, the ticks of every chunk go in a single list, and so do the biomes of the chunks that need any. Worlds
+// without either get an empty compound.
 func (w *slimeWriter) writeExtra() (err error) {
-	// Write empty NBT tag compound
-	var empty map[string]interface{}
-	return w.writeCompressedNbt(empty)
+	var tileTicks, liquidTicks, biomes []NBTCompound
+	for _, coord := range w.world.getSlimeSortedChunkKeys() {
+		chunk := w.world.chunks[coord]
+		tileTicks = append(tileTicks, chunk.TileTicks...)
+		liquidTicks = append(liquidTicks, chunk.LiquidTicks...)
+		if chunkBiomes := chunk.extraBiomes(); chunkBiomes != nil {
+			biomes = append(biomes, chunkBiomes)
+		}
+	}
+
+	extra := make(map[string]interface{})
+	if len(tileTicks) > 0 {
+		extra[slimeExtraTileTicks] = tileTicks
+	}
+	if len(liquidTicks) > 0 {
+		extra[slimeExtraLiquidTicks] = liquidTicks
+	}
+	if len(biomes) > 0 {
+		extra[slimeExtraBiomes] = biomes
+	}
+	return w.writeCompressedNbt(extra)
 }
 
 func (world *AnvilWorld) getChunkKeys() []ChunkCoord {
@@ -240,6 +501,19 @@ func (world *AnvilWorld) getChunkKeys() []ChunkCoord {
 	return keys
 }
 
+// getSlimeSortedChunkKeys returns the coordinates of every chunk in the order Slime stores them: row by row along Z,
+// then along X within a row, matching the order of the populated chunk bitset.
+func (world *AnvilWorld) getSlimeSortedChunkKeys() []ChunkCoord {
+	keys := world.getChunkKeys()
+	sort.Slice(keys, func(one, two int) bool {
+		if keys[one].Z != keys[two].Z {
+			return keys[one].Z < keys[two].Z
+		}
+		return keys[one].X < keys[two].X
+	})
+	return keys
+}
+
 func (world *AnvilWorld) getMinXZ() (minX int, maxX int, minZ int, maxZ int) {
 	keys := world.getChunkKeys()
 
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// newTestChunk creates a chunk shaped like one decoded from an Anvil region.
+func newTestChunk(x, z int) MinecraftChunk {
+	blocks := make([]byte, 4096)
+	for i := 0; i < 256; i++ {
+		blocks[i] = 1
+	}
+	return MinecraftChunk{
+		X: x,
+		Z: z,
+		Entities: []NBTCompound{
+			{
+				"id":       "minecraft:pig",
+				"Pos":      []interface{}{float64(x*16) + 0.5, float64(1), float64(z*16) + 0.5},
+				"Rotation": []interface{}{float32(0), float32(0)},
+				"Health":   float32(10),
+			},
+		},
+		TileEntities: []NBTCompound{
+			{
+				"id":         "minecraft:sign",
+				"x":          int32(x * 16),
+				"y":          int32(1),
+				"z":          int32(z * 16),
+				"Text1":      "hello",
+				"CustomName": "sign",
+			},
+		},
+		Biomes:    make([]byte, 256),
+		HeightMap: make([]int, 256),
+		Sections: []MinecraftChunkSection{{
+			Y:          0,
+			BlockLight: make([]byte, 2048),
+			Blocks:     blocks,
+			Data:       make([]byte, 2048),
+			SkyLight:   make([]byte, 2048),
+		}},
+	}
+}
+
+func newTestWorld() *AnvilWorld {
+	chunks := make(map[ChunkCoord]MinecraftChunk)
+	for x := -3; x < 3; x++ {
+		for z := -2; z < 2; z++ {
+			chunks[ChunkCoord{X: x, Z: z}] = newTestChunk(x, z)
+		}
+	}
+	return &AnvilWorld{chunks: chunks}
+}
+
+func TestWriteAsSlime_deterministic(t *testing.T) {
+	for _, options := range []SlimeWriteOptions{
+		{},
+		{CompressionLevel: zstd.SpeedBestCompression, CompressionWindowSize: 1 << 20, CompressionConcurrency: 4},
+	} {
+		var first bytes.Buffer
+		if err := newTestWorld().WriteAsSlime(context.Background(), &first, options); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 10; i++ {
+			var again bytes.Buffer
+			if err := newTestWorld().WriteAsSlime(context.Background(), &again, options); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(first.Bytes(), again.Bytes()) {
+				t.Fatalf("conversion %d with %+v produced different bytes than the first conversion", i+2, options)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+// TileCoord identifies a tile in a grid of tiles. Tile 0,0 starts at chunk 0,0.
+type TileCoord struct {
+	X int
+	Z int
+}
+
+// Tile is a part of a world cut out by Split.
+type Tile struct {
+	Coord TileCoord
+	World *AnvilWorld
+}
+
+// Split divides the world into tiles of width by depth chunks, aligned so that chunk 0,0 is at the corner of a tile.
+// Only tiles that contain chunks are returned, ordered by Z and then X. The tiles share their chunks with the world.
+func (world *AnvilWorld) Split(width, depth int) ([]Tile, error) {
+	if width < 1 || depth < 1 {
+		return nil, errors.New("tiles must be at least one chunk wide and deep")
+	}
+
+	byTile := make(map[TileCoord]*AnvilWorld)
+	for coord, chunk := range world.chunks {
+		tileCoord := TileCoord{X: floorDiv(coord.X, width), Z: floorDiv(coord.Z, depth)}
+		tile, ok := byTile[tileCoord]
+		if !ok {
+			tile = &AnvilWorld{chunks: make(map[ChunkCoord]MinecraftChunk)}
+			byTile[tileCoord] = tile
+		}
+		tile.chunks[coord] = chunk
+	}
+
+	tiles := make([]Tile, 0, len(byTile))
+	for coord, tile := range byTile {
+		tiles = append(tiles, Tile{Coord: coord, World: tile})
+	}
+	sort.Slice(tiles, func(one, two int) bool {
+		if tiles[one].Coord.Z != tiles[two].Coord.Z {
+			return tiles[one].Coord.Z < tiles[two].Coord.Z
+		}
+		return tiles[one].Coord.X < tiles[two].Coord.X
+	})
+	return tiles, nil
+}
+
+// floorDiv divides, rounding toward negative infinity like an arithmetic shift does.
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
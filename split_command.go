@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+var splitCommand = &cli.Command{
+	Name:      "split",
+	Usage:     "splits a world into a grid of Slime worlds",
+	ArgsUsage: "WORLD",
+	Description: "WORLD is an Anvil world directory or a Slime file. One Slime file is written for every tile that " +
+		"contains chunks, named after the world and the tile's position in the grid, along with a manifest " +
+		"describing the layout.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "tile-size",
+			Value: "32x32",
+			Usage: "makes each tile `WIDTHxDEPTH` chunks",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "writes the tiles and manifest to `DIRECTORY` (default: next to the world)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return errors.New("need exactly one world to split")
+		}
+		width, depth, err := parseTileSize(c.String("tile-size"))
+		if err != nil {
+			return err
+		}
+		options, err := conversionOptionsFromFlags(c)
+		if err != nil {
+			return err
+		}
+		return splitWorld(c.Context, c.Args().First(), width, depth, options)
+	},
+}
+
+func parseTileSize(size string) (width, depth int, err error) {
+	parts := strings.Split(strings.ToLower(size), "x")
+	if len(parts) == 2 {
+		width, err = strconv.Atoi(parts[0])
+		if err == nil {
+			depth, err = strconv.Atoi(parts[1])
+		}
+	}
+	if len(parts) != 2 || err != nil || width < 1 || depth < 1 {
+		return 0, 0, fmt.Errorf("invalid tile size %q (expected WIDTHxDEPTH, i.e. 16x16)", size)
+	}
+	return
+}
+
+// SplitManifest describes the tiles written by the split command.
+type SplitManifest struct {
+	Source     string              `json:"source"`
+	TileWidth  int                 `json:"tileWidth"`
+	TileDepth  int                 `json:"tileDepth"`
+	ChunkCount int                 `json:"chunkCount"`
+	Tiles      []SplitManifestTile `json:"tiles"`
+}
+
+// SplitManifestTile describes one tile. MinChunkX, MinChunkZ, Width and Depth are the bounds of the chunks the tile
+// actually has, which may be smaller than the tile itself.
+type SplitManifestTile struct {
+	File       string `json:"file"`
+	TileX      int    `json:"tileX"`
+	TileZ      int    `json:"tileZ"`
+	MinChunkX  int    `json:"minChunkX"`
+	MinChunkZ  int    `json:"minChunkZ"`
+	Width      int    `json:"width"`
+	Depth      int    `json:"depth"`
+	ChunkCount int    `json:"chunkCount"`
+}
+
+func splitWorld(ctx context.Context, path string, width, depth int, options conversionOptions) error {
+	world, err := OpenWorld(ctx, path, options.Anvil, newProgressReporter())
+	if err != nil {
+		return err
+	}
+	if err = prepareWorld(world, options); err != nil {
+		return err
+	}
+	tiles, err := world.Split(width, depth)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".slime")
+	outputDir := options.Output
+	if outputDir == "" {
+		outputDir = filepath.Dir(path)
+	}
+	if err = os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+
+	manifest := SplitManifest{Source: path, TileWidth: width, TileDepth: depth, ChunkCount: len(world.chunks)}
+	for _, tile := range tiles {
+		minChunkXZ, tileWidth, tileDepth := tile.World.determineChunkBounds()
+		file := fmt.Sprintf("%s_%d_%d.slime", name, tile.Coord.X, tile.Coord.Z)
+		if err = saveSlimeWorld(ctx, tile.World, filepath.Join(outputDir, file), options); err != nil {
+			return fmt.Errorf("could not write tile %d,%d: %w", tile.Coord.X, tile.Coord.Z, err)
+		}
+		manifest.Tiles = append(manifest.Tiles, SplitManifestTile{
+			File:       file,
+			TileX:      tile.Coord.X,
+			TileZ:      tile.Coord.Z,
+			MinChunkX:  minChunkXZ.X,
+			MinChunkZ:  minChunkXZ.Z,
+			Width:      tileWidth,
+			Depth:      tileDepth,
+			ChunkCount: len(tile.World.chunks),
+		})
+	}
+
+	manifestFile, err := createAtomicFile(filepath.Join(outputDir, name+".manifest.json"), options.NoClobber)
+	if err != nil {
+		return err
+	}
+	defer manifestFile.Abort()
+	encoder := json.NewEncoder(manifestFile)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(manifest); err != nil {
+		return err
+	}
+	if err = manifestFile.Commit(); err != nil {
+		return err
+	}
+	logger.Info("split world", "tiles", len(tiles), "manifest", manifestFile.destination)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestSplit(t *testing.T) {
+	world := &AnvilWorld{chunks: make(map[ChunkCoord]MinecraftChunk)}
+	for _, coord := range []ChunkCoord{{X: -3, Z: 0}, {X: -1, Z: -1}, {X: 0, Z: 0}, {X: 1, Z: 1}, {X: 2, Z: 0}} {
+		world.chunks[coord] = newTestChunk(coord.X, coord.Z)
+	}
+
+	tiles, err := world.Split(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[TileCoord][]ChunkCoord{
+		{X: -1, Z: -1}: {{X: -1, Z: -1}},
+		{X: -2, Z: 0}:  {{X: -3, Z: 0}},
+		{X: 0, Z: 0}:   {{X: 0, Z: 0}, {X: 1, Z: 1}},
+		{X: 1, Z: 0}:   {{X: 2, Z: 0}},
+	}
+	if len(tiles) != len(expected) {
+		t.Fatalf("expected %d tiles, got %d", len(expected), len(tiles))
+	}
+	for i, tile := range tiles {
+		if i > 0 {
+			previous := tiles[i-1].Coord
+			if previous.Z > tile.Coord.Z || (previous.Z == tile.Coord.Z && previous.X >= tile.Coord.X) {
+				t.Errorf("tile %v is out of order after %v", tile.Coord, previous)
+			}
+		}
+		chunks := expected[tile.Coord]
+		if len(tile.World.chunks) != len(chunks) {
+			t.Errorf("tile %v: expected %d chunks, got %d", tile.Coord, len(chunks), len(tile.World.chunks))
+		}
+		for _, coord := range chunks {
+			if _, ok := tile.World.chunks[coord]; !ok {
+				t.Errorf("tile %v is missing chunk %v", tile.Coord, coord)
+			}
+		}
+	}
+
+	if _, err = world.Split(0, 2); err == nil {
+		t.Error("expected an error for an empty tile size")
+	}
+}
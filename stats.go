@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sort"
+
+	"github.com/astei/anvil2slime/nbt"
+)
+
+// WorldStats summarizes what a world contains.
+type WorldStats struct {
+	Chunks int `json:"chunks"`
+	// Blocks counts every block that is not air by name. Legacy blocks are named as 1.13 would name them.
+	Blocks       []NamedCount `json:"blocks"`
+	TileEntities []NamedCount `json:"tileEntities"`
+	Entities     []NamedCount `json:"entities"`
+	// Sections counts the sections at each Y level that have any blocks other than air.
+	Sections   []SectionCount `json:"sections"`
+	ChunkSizes []ChunkSize    `json:"chunkSizes"`
+}
+
+// NamedCount is how many of one kind of block, tile entity or entity a world has. Counts are sorted from most to
+// least common.
+type NamedCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// SectionCount is how many non-empty sections a world has at one Y level.
+type SectionCount struct {
+	Y     int `json:"y"`
+	Count int `json:"count"`
+}
+
+// ChunkSize is how many bytes each part of a chunk takes up before compression, as written to a Slime file. Sizes are
+// sorted from the largest chunk to the smallest.
+type ChunkSize struct {
+	X               int `json:"x"`
+	Z               int `json:"z"`
+	BlockBytes      int `json:"blockBytes"`
+	TileEntityBytes int `json:"tileEntityBytes"`
+	EntityBytes     int `json:"entityBytes"`
+	TotalBytes      int `json:"totalBytes"`
+}
+
+// unknownID is what Stats calls tile entities and entities without an id.
+const unknownID = "(none)"
+
+// Stats counts the blocks, tile entities, entities and non-empty sections of the world, and measures each chunk.
+func (world *AnvilWorld) Stats() (WorldStats, error) {
+	blocks := make(map[string]int)
+	tileEntities := make(map[string]int)
+	entities := make(map[string]int)
+	sections := make(map[int]int)
+	stats := WorldStats{Chunks: len(world.chunks), Sections: []SectionCount{}, ChunkSizes: []ChunkSize{}}
+
+	flattened := world.IsFlattened()
+	for _, coord := range world.getSlimeSortedChunkKeys() {
+		chunk := world.chunks[coord]
+		for _, section := range chunk.Sections {
+			if countSectionBlocks(section, blocks) > 0 {
+				sections[int(section.Y)]++
+			}
+		}
+		for _, tileEntity := range chunk.TileEntities {
+			tileEntities[idOrUnknown(tileEntity)]++
+		}
+		for _, entity := range chunk.Entities {
+			entities[idOrUnknown(entity)]++
+		}
+
+		size, err := measureChunk(chunk, flattened)
+		if err != nil {
+			return stats, err
+		}
+		stats.ChunkSizes = append(stats.ChunkSizes, size)
+	}
+
+	stats.Blocks = sortedCounts(blocks)
+	stats.TileEntities = sortedCounts(tileEntities)
+	stats.Entities = sortedCounts(entities)
+	for y, count := range sections {
+		stats.Sections = append(stats.Sections, SectionCount{Y: y, Count: count})
+	}
+	sort.Slice(stats.Sections, func(i, j int) bool { return stats.Sections[i].Y < stats.Sections[j].Y })
+	sort.SliceStable(stats.ChunkSizes, func(i, j int) bool {
+		return stats.ChunkSizes[i].TotalBytes > stats.ChunkSizes[j].TotalBytes
+	})
+	return stats, nil
+}
+
+func idOrUnknown(compound NBTCompound) string {
+	if id := compound.ID(); id != "" {
+		return id
+	}
+	return unknownID
+}
+
+// countSectionBlocks adds the blocks in a section that are not air to counts, and returns how many there were.
+func countSectionBlocks(section MinecraftChunkSection, counts map[string]int) int {
+	total := 0
+	if section.usesPalette() {
+		perEntry := make([]int, len(section.Palette))
+		for _, p := range unpackBlockStates(section.BlockStates, len(section.BlockStates)/64, 4096) {
+			if p < len(perEntry) {
+				perEntry[p]++
+			}
+		}
+		for p, entry := range section.Palette {
+			if name := blockStateFromPaletteEntry(entry).Name; perEntry[p] > 0 && !isAir(name) {
+				counts[name] += perEntry[p]
+				total += perEntry[p]
+			}
+		}
+		return total
+	}
+
+	var perBlock [256 * 16]int
+	for idx, id := range section.Blocks {
+		if id != 0 {
+			perBlock[int(id)<<4|int(getNibble(section.Data, idx))]++
+		}
+	}
+	for key, count := range perBlock {
+		if count == 0 {
+			continue
+		}
+		if name := flattenLegacyBlock(byte(key>>4), byte(key&15)).Name; !isAir(name) {
+			counts[name] += count
+			total += count
+		}
+	}
+	return total
+}
+
+// measureChunk encodes a chunk the way a Slime file would, without compressing it, to find out how large it is.
+func measureChunk(chunk MinecraftChunk, flattened bool) (ChunkSize, error) {
+	size := ChunkSize{X: chunk.X, Z: chunk.Z}
+	writer := &slimeWriter{version: slimeDefaultVersion, flattened: flattened}
+	if flattened {
+		writer.version = slimeLatestVersion
+	}
+
+	blocks := &countingWriter{writer: ioutil.Discard}
+	if err := writer.writeChunkHeader(chunk, blocks); err != nil {
+		return size, err
+	}
+	for _, section := range chunk.Sections {
+		if err := writer.writeChunkSection(section, false, blocks); err != nil {
+			return size, err
+		}
+	}
+	size.BlockBytes = int(blocks.n)
+
+	var err error
+	if size.TileEntityBytes, err = encodedSize(chunk.TileEntities); err != nil {
+		return size, err
+	}
+	if size.EntityBytes, err = encodedSize(chunk.Entities); err != nil {
+		return size, err
+	}
+	size.TotalBytes = size.BlockBytes + size.TileEntityBytes + size.EntityBytes
+	return size, nil
+}
+
+func encodedSize(compounds []NBTCompound) (int, error) {
+	total := 0
+	for _, compound := range compounds {
+		var buf bytes.Buffer
+		if err := nbt.NewEncoder(&buf).Encode(compound); err != nil {
+			return 0, err
+		}
+		total += buf.Len()
+	}
+	return total, nil
+}
+
+func sortedCounts(counts map[string]int) []NamedCount {
+	sorted := make([]NamedCount, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, NamedCount{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+// statsTableChunks is how many of the largest chunks the table format lists.
+const statsTableChunks = 10
+
+var statsCommand = &cli.Command{
+	Name:      "stats",
+	Usage:     "reports what a world contains",
+	ArgsUsage: "WORLD",
+	Description: "WORLD is an Anvil world directory or a Slime file. The report counts blocks by type, tile " +
+		"entities and entities by id, and non-empty sections at each Y level, and lists how many bytes each chunk " +
+		"takes up in a Slime file before compression.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "table",
+			Usage: "writes the report as `FORMAT` (table, json or csv)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return errors.New("need exactly one world to report on")
+		}
+		var printStats func(io.Writer, WorldStats) error
+		switch format := c.String("format"); format {
+		case "table":
+			printStats = printStatsTable
+		case "json":
+			printStats = printStatsJSON
+		case "csv":
+			printStats = printStatsCSV
+		default:
+			return fmt.Errorf("unknown report format %q (expected table, json or csv)", format)
+		}
+		options, err := conversionOptionsFromFlags(c)
+		if err != nil {
+			return err
+		}
+		stats, err := worldStats(c.Context, c.Args().First(), options)
+		if err != nil {
+			return err
+		}
+		return printStats(os.Stdout, stats)
+	},
+}
+
+func worldStats(ctx context.Context, path string, options conversionOptions) (WorldStats, error) {
+	world, err := OpenWorld(ctx, path, options.Anvil, newProgressReporter())
+	if err != nil {
+		return WorldStats{}, err
+	}
+	if err = transformWorld(world, options); err != nil {
+		return WorldStats{}, err
+	}
+	return world.Stats()
+}
+
+func printStatsTable(out io.Writer, stats WorldStats) error {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(table, "CHUNKS\t%d\n", stats.Chunks)
+	for _, group := range []struct {
+		heading string
+		counts  []NamedCount
+	}{{"BLOCK", stats.Blocks}, {"TILE ENTITY", stats.TileEntities}, {"ENTITY", stats.Entities}} {
+		_, _ = fmt.Fprintf(table, "\n%s\tCOUNT\n", group.heading)
+		for _, count := range group.counts {
+			_, _ = fmt.Fprintf(table, "%s\t%d\n", count.Name, count.Count)
+		}
+	}
+
+	_, _ = fmt.Fprintln(table, "\nSECTION Y\tNON-EMPTY SECTIONS")
+	for _, section := range stats.Sections {
+		_, _ = fmt.Fprintf(table, "%d\t%d\n", section.Y, section.Count)
+	}
+
+	total := 0
+	for _, size := range stats.ChunkSizes {
+		total += size.TotalBytes
+	}
+	_, _ = fmt.Fprintf(table, "\nLARGEST CHUNKS\tBLOCK BYTES\tTILE ENTITY BYTES\tENTITY BYTES\tTOTAL BYTES\n")
+	for i, size := range stats.ChunkSizes {
+		if i == statsTableChunks {
+			break
+		}
+		_, _ = fmt.Fprintf(table, "%d,%d\t%d\t%d\t%d\t%d\n", size.X, size.Z, size.BlockBytes, size.TileEntityBytes,
+			size.EntityBytes, size.TotalBytes)
+	}
+	_, _ = fmt.Fprintf(table, "all chunks\t\t\t\t%d\n", total)
+	return table.Flush()
+}
+
+func printStatsJSON(out io.Writer, stats WorldStats) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
+
+// printStatsCSV writes the report as a single table of KIND,NAME,COUNT rows, where chunks are named X,Z and list
+// their sizes in the columns after the count of their total size.
+func printStatsCSV(out io.Writer, stats WorldStats) error {
+	header := []string{"kind", "name", "count", "block_bytes", "tile_entity_bytes", "entity_bytes"}
+	writer := csv.NewWriter(out)
+	write := func(fields ...string) {
+		// Every row has the same number of fields, which strict CSV readers insist on.
+		row := make([]string, len(header))
+		copy(row, fields)
+		_ = writer.Write(row)
+	}
+
+	write(header...)
+	write("chunks", "", strconv.Itoa(stats.Chunks))
+	for _, group := range []struct {
+		kind   string
+		counts []NamedCount
+	}{{"block", stats.Blocks}, {"tile_entity", stats.TileEntities}, {"entity", stats.Entities}} {
+		for _, count := range group.counts {
+			write(group.kind, count.Name, strconv.Itoa(count.Count))
+		}
+	}
+	for _, section := range stats.Sections {
+		write("section", strconv.Itoa(section.Y), strconv.Itoa(section.Count))
+	}
+	for _, size := range stats.ChunkSizes {
+		write("chunk_bytes", fmt.Sprintf("%d,%d", size.X, size.Z), strconv.Itoa(size.TotalBytes),
+			strconv.Itoa(size.BlockBytes), strconv.Itoa(size.TileEntityBytes), strconv.Itoa(size.EntityBytes))
+	}
+	writer.Flush()
+	return writer.Error()
+}
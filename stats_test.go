@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	world := newTestWorld()
+	chunk := world.chunks[ChunkCoord{X: 0, Z: 0}]
+	chunk.Sections[0].Blocks[1<<8] = 35
+	setNibble(chunk.Sections[0].Data, 1<<8, 14)
+	chunk.Entities = append(chunk.Entities, NBTCompound{"id": "minecraft:pig"}, NBTCompound{})
+	world.chunks[ChunkCoord{X: 0, Z: 0}] = chunk
+
+	stats, err := world.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Chunks != 24 {
+		t.Errorf("expected 24 chunks, got %d", stats.Chunks)
+	}
+	expectedBlocks := []NamedCount{{"minecraft:stone", 24 * 256}, {"minecraft:red_wool", 1}}
+	if len(stats.Blocks) != len(expectedBlocks) || stats.Blocks[0] != expectedBlocks[0] ||
+		stats.Blocks[1] != expectedBlocks[1] {
+		t.Errorf("expected blocks %v, got %v", expectedBlocks, stats.Blocks)
+	}
+	if len(stats.Entities) != 2 || stats.Entities[0] != (NamedCount{"minecraft:pig", 25}) ||
+		stats.Entities[1] != (NamedCount{unknownID, 1}) {
+		t.Errorf("unexpected entities %v", stats.Entities)
+	}
+	if len(stats.TileEntities) != 1 || stats.TileEntities[0] != (NamedCount{"minecraft:sign", 24}) {
+		t.Errorf("unexpected tile entities %v", stats.TileEntities)
+	}
+	if len(stats.Sections) != 1 || stats.Sections[0] != (SectionCount{Y: 0, Count: 24}) {
+		t.Errorf("unexpected sections %v", stats.Sections)
+	}
+	if largest := stats.ChunkSizes[0]; largest.X != 0 || largest.Z != 0 ||
+		largest.TotalBytes != largest.BlockBytes+largest.TileEntityBytes+largest.EntityBytes ||
+		largest.TotalBytes <= stats.ChunkSizes[1].TotalBytes {
+		t.Errorf("expected chunk 0,0 to be the largest, got %+v", largest)
+	}
+
+	var out bytes.Buffer
+	if err = printStatsCSV(&out, stats); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 1 + 1 + 2 + 1 + 2 + 1 + 24; len(rows) != expected {
+		t.Errorf("expected %d rows, got %d", expected, len(rows))
+	}
+}
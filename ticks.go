@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tickTarget returns the block or fluid a scheduled tick is for. Worlds before 1.8 store it as a numeric block id.
+func tickTarget(tick NBTCompound) string {
+	if name, ok := tick["i"].(string); ok {
+		return name
+	}
+	return fmt.Sprint(tick["i"])
+}
+
+// flattenTicks names the block each scheduled tick of a chunk is for after the block state now at its position, as
+// 1.13 does when it loads an older world, and moves ticks for water and lava to LiquidTicks, since 1.13 ticks them as
+// fluids. Ticks outside the converted sections are left alone.
+func flattenTicks(chunk *MinecraftChunk, legacy *legacyChunk, converted map[int][]BlockState) {
+	var blockTicks []NBTCompound
+	for _, tick := range chunk.TileTicks {
+		x, okX := tick.Int("x")
+		y, okY := tick.Int("y")
+		z, okZ := tick.Int("z")
+		x, z = x-legacy.originX, z-legacy.originZ
+		states, ok := converted[y>>4]
+		if !okX || !okY || !okZ || !ok || y < 0 || x < 0 || x > 15 || z < 0 || z > 15 {
+			blockTicks = append(blockTicks, tick)
+			continue
+		}
+
+		state := states[(y&15)<<8|z<<4|x]
+		if fluid, ok := fluidOf(state); ok {
+			tick["i"] = fluid
+			chunk.LiquidTicks = append(chunk.LiquidTicks, tick)
+		} else {
+			tick["i"] = state.Name
+			blockTicks = append(blockTicks, tick)
+		}
+	}
+	chunk.TileTicks = blockTicks
+}
+
+// fluidOf returns the fluid of a water or lava block: the block's own name for a source, and its flowing variant
+// otherwise.
+func fluidOf(state BlockState) (string, bool) {
+	if state.Name != "minecraft:water" && state.Name != "minecraft:lava" {
+		return "", false
+	}
+	if level := state.Properties["level"]; level != "" && level != "0" {
+		return "minecraft:flowing_" + strings.TrimPrefix(state.Name, "minecraft:"), true
+	}
+	return state.Name, true
+}
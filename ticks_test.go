@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func newTestTick(target string, x, y, z int) NBTCompound {
+	return NBTCompound{"i": target, "x": int32(x), "y": int32(y), "z": int32(z), "t": int32(2), "p": int32(0)}
+}
+
+func TestFlatten_ticks(t *testing.T) {
+	chunk := newTestChunk(1, 0)
+	chunk.Sections[0].Blocks[1<<8|1] = 93 // unpowered repeater
+	chunk.Sections[0].Blocks[1<<8|2] = 9  // water source
+	chunk.Sections[0].Blocks[1<<8|3] = 8  // flowing water
+	setNibble(chunk.Sections[0].Data, 1<<8|3, 2)
+	chunk.TileTicks = []NBTCompound{
+		newTestTick("minecraft:unpowered_repeater", 17, 1, 0),
+		newTestTick("minecraft:water", 18, 1, 0),
+		newTestTick("minecraft:flowing_water", 19, 1, 0),
+		newTestTick("minecraft:redstone_torch", 17, 100, 0),
+	}
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{X: 1, Z: 0}: chunk}}
+	world.Flatten()
+
+	chunk = world.chunks[ChunkCoord{X: 1, Z: 0}]
+	var blockTicks, fluidTicks []string
+	for _, tick := range chunk.TileTicks {
+		blockTicks = append(blockTicks, tickTarget(tick))
+	}
+	for _, tick := range chunk.LiquidTicks {
+		fluidTicks = append(fluidTicks, tickTarget(tick))
+	}
+	if len(blockTicks) != 2 || blockTicks[0] != "minecraft:repeater" || blockTicks[1] != "minecraft:redstone_torch" {
+		t.Errorf("expected ticks for a repeater and the torch outside the chunk's sections, got %v", blockTicks)
+	}
+	if len(fluidTicks) != 2 || fluidTicks[0] != "minecraft:water" || fluidTicks[1] != "minecraft:flowing_water" {
+		t.Errorf("expected ticks for still and flowing water, got %v", fluidTicks)
+	}
+}
+
+func TestWriteAsSlime_ticks(t *testing.T) {
+	world := newTestWorld()
+	chunk := world.chunks[ChunkCoord{X: 1, Z: 1}]
+	chunk.TileTicks = []NBTCompound{
+		newTestTick("minecraft:repeater", 17, 1, 16),
+		newTestTick("minecraft:observer", 31, 1, 31),
+	}
+	chunk.LiquidTicks = []NBTCompound{newTestTick("minecraft:flowing_lava", 20, 1, 20)}
+	world.chunks[ChunkCoord{X: 1, Z: 1}] = chunk
+
+	readBack := writeAndReadBack(t, world, SlimeWriteOptions{})
+	if err := world.VerifySlime(readBack, SlimeWriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	chunk = readBack.chunks[ChunkCoord{X: 1, Z: 1}]
+	if len(chunk.TileTicks) != 2 || len(chunk.LiquidTicks) != 1 {
+		t.Errorf("expected 2 block ticks and 1 fluid tick, got %d and %d", len(chunk.TileTicks), len(chunk.LiquidTicks))
+	}
+	if ticks := readBack.chunks[ChunkCoord{}].TileTicks; ticks != nil {
+		t.Errorf("expected no ticks in chunk 0,0, got %v", ticks)
+	}
+
+	readBack.chunks[ChunkCoord{X: 1, Z: 1}].LiquidTicks[0]["i"] = "minecraft:lava"
+	if err := world.VerifySlime(readBack, SlimeWriteOptions{}); err == nil {
+		t.Error("expected a changed fluid tick to fail verification")
+	}
+
+	world.Relocate(-1, 0)
+	if x, _ := world.chunks[ChunkCoord{X: 0, Z: 1}].TileTicks[0].Int("x"); x != 1 {
+		t.Errorf("expected the tick to move with its chunk to x 1, got %d", x)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+// coordinateTransform maps horizontal positions in the world to new ones. Blocks and chunks are cells: a transform
+// maps the cell as a whole, so that, for instance, mirroring block 0 puts it at -1 rather than at 0.
+type coordinateTransform interface {
+	transformCell(x, z int) (int, int)
+	transformPoint(x, z float64) (float64, float64)
+	transformYaw(yaw float32) float32
+}
+
+// translation moves positions by a number of blocks.
+type translation struct {
+	dx, dz int
+}
+
+func (t translation) transformCell(x, z int) (int, int) {
+	return x + t.dx, z + t.dz
+}
+
+func (t translation) transformPoint(x, z float64) (float64, float64) {
+	return x + float64(t.dx), z + float64(t.dz)
+}
+
+func (t translation) transformYaw(yaw float32) float32 {
+	return yaw
+}
+
+// Relocate moves every chunk in the world by dx and dz chunks. Every absolute position stored in the chunk's tile
+// entities, entities and scheduled ticks moves with it, so that leashes, beds, spawners and the like keep pointing at
+// the same blocks.
+func (world *AnvilWorld) Relocate(dx, dz int) {
+	if dx == 0 && dz == 0 {
+		return
+	}
+
+	blocks := translation{dx: dx * 16, dz: dz * 16}
+	moved := make(map[ChunkCoord]MinecraftChunk, len(world.chunks))
+	for coord, chunk := range world.chunks {
+		chunk.X += dx
+		chunk.Z += dz
+		transformChunkEntities(&chunk, blocks)
+		moved[ChunkCoord{X: coord.X + dx, Z: coord.Z + dz}] = chunk
+	}
+	world.chunks = moved
+}
+
+// Recenter moves the world so that its chunks are centered around chunk 0,0 and returns how far it was moved.
+func (world *AnvilWorld) Recenter() (dx, dz int) {
+	if len(world.chunks) == 0 {
+		return 0, 0
+	}
+	minChunkXZ, width, depth := world.determineChunkBounds()
+	dx = -(minChunkXZ.X + (width-1)/2)
+	dz = -(minChunkXZ.Z + (depth-1)/2)
+	world.Relocate(dx, dz)
+	return
+}
+
+func transformChunkEntities(chunk *MinecraftChunk, t coordinateTransform) {
+	for _, tileEntity := range chunk.TileEntities {
+		transformTileEntity(tileEntity, t)
+	}
+	for _, tick := range chunk.TileTicks {
+		transformIntTagPosition(tick, "x", "z", t)
+	}
+	for _, tick := range chunk.LiquidTicks {
+		transformIntTagPosition(tick, "x", "z", t)
+	}
+	for _, entity := range chunk.Entities {
+		transformEntity(entity, t)
+	}
+}
+
+// absoluteBlockPositionTags lists the sets of integer tags that hold an absolute block position directly inside an
+// entity or tile entity.
+var absoluteBlockPositionTags = [][3]string{
+	{"x", "y", "z"},                         // tile entities
+	{"TileX", "TileY", "TileZ"},             // paintings, item frames and leash knots
+	{"APX", "APY", "APZ"},                   // shulker attachment
+	{"SleepingX", "SleepingY", "SleepingZ"}, // beds
+	{"SpawnX", "SpawnY", "SpawnZ"},          // player respawn points
+	{"HomePosX", "HomePosY", "HomePosZ"},    // turtles
+	{"TravelPosX", "TravelPosY", "TravelPosZ"},
+	{"BoundX", "BoundY", "BoundZ"}, // vexes
+	{"AX", "AY", "AZ"},             // phantoms
+}
+
+// absoluteBlockPositionCompounds lists the compounds that hold an absolute block position as X, Y and Z tags.
+var absoluteBlockPositionCompounds = []string{
+	"Leash",        // leashed to a fence
+	"BeamTarget",   // end crystals
+	"HivePos",      // bees
+	"FlowerPos",    // bees and beehives
+	"WanderTarget", // wandering traders
+	"PatrolTarget", // raiders
+	"ExitPortal",   // end gateways
+}
+
+func transformTileEntity(tileEntity NBTCompound, t coordinateTransform) {
+	transformBlockPositions(tileEntity, t)
+
+	// Spawners and beehives store whole entities that are placed in the world later.
+	if spawnData, ok := tileEntity.Compound("SpawnData"); ok {
+		transformSpawnData(spawnData, t)
+	}
+	for _, potential := range tileEntity.CompoundList("SpawnPotentials") {
+		if entity, ok := potential.Compound("Entity"); ok {
+			transformEntity(entity, t)
+		}
+		if data, ok := potential.Compound("data"); ok {
+			transformSpawnData(data, t)
+		}
+	}
+	for _, bee := range tileEntity.CompoundList("Bees") {
+		if entity, ok := bee.Compound("EntityData"); ok {
+			transformEntity(entity, t)
+		}
+	}
+}
+
+// transformSpawnData handles both the pre-1.18 layout, where SpawnData is the entity, and the newer one that nests
+// the entity in an entity compound.
+func transformSpawnData(spawnData NBTCompound, t coordinateTransform) {
+	if entity, ok := spawnData.Compound("entity"); ok {
+		transformEntity(entity, t)
+	} else {
+		transformEntity(spawnData, t)
+	}
+}
+
+func transformEntity(entity NBTCompound, t coordinateTransform) {
+	if pos, ok := entity["Pos"].([]interface{}); ok && len(pos) == 3 {
+		x, okX := pos[0].(float64)
+		z, okZ := pos[2].(float64)
+		if okX && okZ {
+			pos[0], pos[2] = t.transformPoint(x, z)
+		}
+	}
+	if rotation, ok := entity["Rotation"].([]interface{}); ok && len(rotation) == 2 {
+		if yaw, ok := rotation[0].(float32); ok {
+			rotation[0] = t.transformYaw(yaw)
+		}
+	}
+	transformBlockPositions(entity, t)
+
+	for _, passenger := range entity.CompoundList("Passengers") {
+		transformEntity(passenger, t)
+	}
+	if riding, ok := entity.Compound("Riding"); ok {
+		transformEntity(riding, t)
+	}
+
+	// Villagers and other mobs with a brain remember positions such as their home and job site.
+	if brain, ok := entity.Compound("Brain"); ok {
+		if memories, ok := brain.Compound("memories"); ok {
+			for name := range memories {
+				memory, ok := memories.Compound(name)
+				if !ok {
+					continue
+				}
+				if value, ok := memory.Compound("value"); ok {
+					transformIntArrayPosition(value, "pos", t)
+				}
+			}
+		}
+	}
+}
+
+func transformBlockPositions(compound NBTCompound, t coordinateTransform) {
+	for _, tags := range absoluteBlockPositionTags {
+		transformIntTagPosition(compound, tags[0], tags[2], t)
+	}
+	for _, name := range absoluteBlockPositionCompounds {
+		// Newer versions store some of these as an int array instead of a compound.
+		if position, ok := compound.Compound(name); ok {
+			transformIntTagPosition(position, "X", "Z", t)
+		} else {
+			transformIntArrayPosition(compound, name, t)
+		}
+	}
+}
+
+func transformIntTagPosition(compound NBTCompound, xTag, zTag string, t coordinateTransform) {
+	x, okX := compound.Int(xTag)
+	z, okZ := compound.Int(zTag)
+	if okX && okZ {
+		x, z = t.transformCell(x, z)
+		compound.SetInt(xTag, x)
+		compound.SetInt(zTag, z)
+	}
+}
+
+func transformIntArrayPosition(compound NBTCompound, name string, t coordinateTransform) {
+	if position, ok := compound[name].([]int32); ok && len(position) == 3 {
+		x, z := t.transformCell(int(position[0]), int(position[2]))
+		position[0], position[2] = int32(x), int32(z)
+	}
+}
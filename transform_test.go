@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateSlimeBounds(t *testing.T) {
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{
+		{X: -2, Z: 40000}: newTestChunk(-2, 40000),
+		{X: 3, Z: 40005}:  newTestChunk(3, 40005),
+	}}
+	if err := world.ValidateSlimeBounds(); !errors.Is(err, ErrSlimeOutOfBounds) {
+		t.Fatalf("expected ErrSlimeOutOfBounds, got %v", err)
+	}
+
+	wide := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{
+		{X: -20000, Z: 0}: newTestChunk(-20000, 0),
+		{X: 20000, Z: 0}:  newTestChunk(20000, 0),
+	}}
+	if err := wide.ValidateSlimeBounds(); !errors.Is(err, ErrSlimeOutOfBounds) {
+		t.Fatalf("expected ErrSlimeOutOfBounds, got %v", err)
+	}
+
+	if err := (&AnvilWorld{}).ValidateSlimeBounds(); err != ErrEmptyWorld {
+		t.Fatalf("expected ErrEmptyWorld, got %v", err)
+	}
+}
+
+func TestRecenter(t *testing.T) {
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{
+		{X: -2, Z: 40000}: newTestChunk(-2, 40000),
+		{X: 3, Z: 40005}:  newTestChunk(3, 40005),
+	}}
+	dx, dz := world.Recenter()
+	if dx != 0 || dz != -40002 {
+		t.Fatalf("got offset %d,%d, want 0,-40002", dx, dz)
+	}
+	if err := world.ValidateSlimeBounds(); err != nil {
+		t.Fatal(err)
+	}
+
+	chunk, ok := world.chunks[ChunkCoord{X: 3, Z: 3}]
+	if !ok || chunk.X != 3 || chunk.Z != 3 {
+		t.Fatalf("chunk was not moved to 3,3: %+v", world.getChunkKeys())
+	}
+	tileEntity := chunk.TileEntities[0]
+	if tileEntity["z"] != int32(3*16) {
+		t.Errorf("tile entity z is %v, want %d", tileEntity["z"], 3*16)
+	}
+	pos := chunk.Entities[0]["Pos"].([]interface{})
+	if pos[2] != float64(3*16)+0.5 {
+		t.Errorf("entity z is %v, want %v", pos[2], 3*16+0.5)
+	}
+}
+
+func TestRelocate_nestedPositions(t *testing.T) {
+	chunk := newTestChunk(0, 0)
+	chunk.Entities = []NBTCompound{{
+		"id":    "minecraft:horse",
+		"Pos":   []interface{}{float64(1.5), float64(64), float64(2.5)},
+		"Leash": map[string]interface{}{"X": int32(3), "Y": int32(64), "Z": int32(4)},
+		"Passengers": []interface{}{
+			map[string]interface{}{"id": "minecraft:zombie", "Pos": []interface{}{float64(1.5), float64(65), float64(2.5)}},
+		},
+	}, {
+		"id":    "minecraft:item_frame",
+		"TileX": int32(5),
+		"TileY": int32(64),
+		"TileZ": int32(6),
+	}}
+	chunk.TileEntities = []NBTCompound{{
+		"id": "minecraft:mob_spawner",
+		"x":  int32(7),
+		"y":  int32(64),
+		"z":  int32(8),
+		"SpawnPotentials": []interface{}{
+			map[string]interface{}{"Entity": map[string]interface{}{
+				"id":  "minecraft:pig",
+				"Pos": []interface{}{float64(7.5), float64(65), float64(8.5)},
+			}},
+		},
+	}}
+	world := &AnvilWorld{chunks: map[ChunkCoord]MinecraftChunk{{X: 0, Z: 0}: chunk}}
+	world.Relocate(2, -1)
+
+	moved := world.chunks[ChunkCoord{X: 2, Z: -1}]
+	horse := moved.Entities[0]
+	if pos := horse["Pos"].([]interface{}); pos[0] != float64(33.5) || pos[2] != float64(-13.5) {
+		t.Errorf("horse was moved to %v", pos)
+	}
+	if leash, _ := horse.Compound("Leash"); leash["X"] != int32(35) || leash["Z"] != int32(-12) {
+		t.Errorf("leash was moved to %v", leash)
+	}
+	if pos := horse.CompoundList("Passengers")[0]["Pos"].([]interface{}); pos[0] != float64(33.5) || pos[2] != float64(-13.5) {
+		t.Errorf("passenger was moved to %v", pos)
+	}
+	if frame := moved.Entities[1]; frame["TileX"] != int32(37) || frame["TileZ"] != int32(-10) {
+		t.Errorf("item frame was moved to %v,%v", frame["TileX"], frame["TileZ"])
+	}
+
+	spawner := moved.TileEntities[0]
+	if spawner["x"] != int32(39) || spawner["z"] != int32(-8) {
+		t.Errorf("spawner was moved to %v,%v", spawner["x"], spawner["z"])
+	}
+	spawned, _ := spawner.CompoundList("SpawnPotentials")[0].Compound("Entity")
+	if pos := spawned["Pos"].([]interface{}); pos[0] != float64(39.5) || pos[2] != float64(-7.5) {
+		t.Errorf("spawner entity was moved to %v", pos)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var ErrVerificationFailed = errors.New("slime world does not match the world that was written")
+
+// maxReportedMismatches is how many mismatches a verification error lists before summarizing the rest.
+const maxReportedMismatches = 20
+
+// VerifySlime compares a world read back from a Slime file with the world that was written to it using options, and
+// returns an error listing every mismatch. Data that the format does not keep is expected to come back the way the
+// writer leaves it: light that was left out, height maps that were derived or calculated and tile entities, entities
+// and scheduled ticks that are read back into the chunk they are positioned in.
+func (world *AnvilWorld) VerifySlime(readBack *AnvilWorld, options SlimeWriteOptions) error {
+	flattened := world.IsFlattened()
+	v := &slimeVerifier{version: options.version(flattened), light: options.Light, flattened: flattened}
+
+	expectedTileEntities := make(map[ChunkCoord][]NBTCompound)
+	expectedTileTicks := make(map[ChunkCoord][]NBTCompound)
+	expectedLiquidTicks := make(map[ChunkCoord][]NBTCompound)
+	expectedEntities := make(map[ChunkCoord][]NBTCompound)
+	for _, coord := range world.getSlimeSortedChunkKeys() {
+		chunk := world.chunks[coord]
+		groupByBlockPosition(chunk.TileEntities, expectedTileEntities)
+		groupByBlockPosition(chunk.TileTicks, expectedTileTicks)
+		groupByBlockPosition(chunk.LiquidTicks, expectedLiquidTicks)
+		for _, entity := range chunk.Entities {
+			if owner, ok := entityChunk(entity); ok {
+				expectedEntities[owner] = append(expectedEntities[owner], entity)
+			}
+		}
+	}
+
+	for _, coord := range world.getSlimeSortedChunkKeys() {
+		actual, ok := readBack.chunks[coord]
+		if !ok {
+			v.mismatch(coord, "chunk is missing")
+			continue
+		}
+		v.verifyChunk(coord, world.chunks[coord], actual)
+		v.verifyCompounds(coord, "tile entity", "tile entities", NBTCompound.ID, expectedTileEntities[coord],
+			actual.TileEntities)
+		v.verifyCompounds(coord, "block tick", "block ticks", tickTarget, expectedTileTicks[coord], actual.TileTicks)
+		v.verifyCompounds(coord, "fluid tick", "fluid ticks", tickTarget, expectedLiquidTicks[coord],
+			actual.LiquidTicks)
+		v.verifyCompounds(coord, "entity", "entities", NBTCompound.ID, expectedEntities[coord], actual.Entities)
+	}
+	for _, coord := range readBack.getSlimeSortedChunkKeys() {
+		if _, ok := world.chunks[coord]; !ok {
+			v.mismatch(coord, "chunk was never written")
+		}
+	}
+	return v.err()
+}
+
+// groupByBlockPosition adds compounds that hold their block position in x and z tags, such as tile entities, to the
+// chunk they are in.
+func groupByBlockPosition(compounds []NBTCompound, byChunk map[ChunkCoord][]NBTCompound) {
+	for _, compound := range compounds {
+		x, _ := compound.Int("x")
+		z, _ := compound.Int("z")
+		owner := ChunkCoord{X: x >> 4, Z: z >> 4}
+		byChunk[owner] = append(byChunk[owner], compound)
+	}
+}
+
+// entityChunk returns the chunk an entity is in according to its position.
+func entityChunk(entity NBTCompound) (ChunkCoord, bool) {
+	pos, ok := entity["Pos"].([]interface{})
+	if !ok || len(pos) != 3 {
+		return ChunkCoord{}, false
+	}
+	x, _ := pos[0].(float64)
+	z, _ := pos[2].(float64)
+	return ChunkCoord{X: int(math.Floor(x)) >> 4, Z: int(math.Floor(z)) >> 4}, true
+}
+
+type slimeVerifier struct {
+	version    uint8
+	light      LightMode
+	flattened  bool
+	mismatches []string
+}
+
+func (v *slimeVerifier) mismatch(coord ChunkCoord, format string, args ...interface{}) {
+	v.mismatches = append(v.mismatches, fmt.Sprintf("chunk %d,%d: ", coord.X, coord.Z)+fmt.Sprintf(format, args...))
+}
+
+func (v *slimeVerifier) err() error {
+	if len(v.mismatches) == 0 {
+		return nil
+	}
+	var report strings.Builder
+	fmt.Fprintf(&report, "%v (%d mismatches)", ErrVerificationFailed, len(v.mismatches))
+	for i, mismatch := range v.mismatches {
+		if i == maxReportedMismatches {
+			fmt.Fprintf(&report, "\n  and %d more", len(v.mismatches)-i)
+			break
+		}
+		report.WriteString("\n  " + mismatch)
+	}
+	return verificationError{report: report.String()}
+}
+
+// verificationError carries the mismatch report while still matching ErrVerificationFailed.
+type verificationError struct {
+	report string
+}
+
+func (e verificationError) Error() string {
+	return e.report
+}
+
+func (e verificationError) Unwrap() error {
+	return ErrVerificationFailed
+}
+
+func (v *slimeVerifier) verifyChunk(coord ChunkCoord, expected, actual MinecraftChunk) {
+	switch {
+	case v.flattened:
+		for _, name := range expected.heightMapNames() {
+			v.compareInts(coord, name+" height map", expected.HeightMaps[name], actual.HeightMaps[name])
+		}
+		for _, name := range actual.heightMapNames() {
+			if _, ok := expected.HeightMaps[name]; !ok {
+				v.mismatch(coord, "%s height map was never written", name)
+			}
+		}
+	case len(expected.HeightMap) == 256:
+		v.compareInts(coord, "height map", expected.HeightMap, actual.HeightMap)
+	default:
+		v.compareInts(coord, "derived height map", expected.legacyHeightMap(), actual.HeightMap)
+	}
+	v.compareBytes(coord, "biomes", expected.Biomes, actual.Biomes)
+	v.compareCustomBiomes(coord, "column", 256, expected.CustomBiomes, actual.CustomBiomes)
+	v.compareBytes(coord, "biome cells", expected.BiomeCells, actual.BiomeCells)
+	v.compareCustomBiomes(coord, "cell", biomeCellsPerChunk, expected.CustomBiomeCells, actual.CustomBiomeCells)
+
+	actualSections := make(map[uint8]MinecraftChunkSection, len(actual.Sections))
+	for _, section := range actual.Sections {
+		actualSections[section.Y] = section
+	}
+	stripLight := v.light.strips(expected)
+	for _, section := range expected.Sections {
+		actualSection, ok := actualSections[section.Y]
+		if !ok {
+			v.mismatch(coord, "section %d is missing", section.Y)
+			continue
+		}
+		delete(actualSections, section.Y)
+
+		name := fmt.Sprintf("section %d", section.Y)
+		v.compareBytes(coord, name+" block light", v.expectedLight(section.BlockLight, stripLight),
+			actualSection.BlockLight)
+		v.compareBytes(coord, name+" sky light", v.expectedLight(section.SkyLight, stripLight),
+			actualSection.SkyLight)
+		if section.usesPalette() {
+			if len(section.Palette) != len(actualSection.Palette) {
+				v.mismatch(coord, "%s palette has %d entries, expected %d", name, len(actualSection.Palette),
+					len(section.Palette))
+			} else {
+				for i := range section.Palette {
+					v.compareNBT(coord, fmt.Sprintf("%s palette entry %d", name, i), section.Palette[i],
+						actualSection.Palette[i])
+				}
+			}
+			v.compareLongs(coord, name+" block states", section.BlockStates, actualSection.BlockStates)
+		} else {
+			v.compareBytes(coord, name+" blocks", section.Blocks, actualSection.Blocks)
+			v.compareBytes(coord, name+" block data", section.Data, actualSection.Data)
+		}
+	}
+	for y := range actualSections {
+		v.mismatch(coord, "section %d was never written", y)
+	}
+}
+
+// compareCustomBiomes compares the names of the biomes without a numeric id of every column or cell.
+func (v *slimeVerifier) compareCustomBiomes(coord ChunkCoord, what string, length int,
+	expected, actual map[int]string) {
+	for i := 0; i < length; i++ {
+		if expected[i] != actual[i] {
+			v.mismatch(coord, "custom biome of %s %d is %q, expected %q", what, i, actual[i], expected[i])
+		}
+	}
+}
+
+// expectedLight returns the light a section should be read back with. Versions before 5 store left out light as
+// zeroes, and later versions leave it out entirely.
+func (v *slimeVerifier) expectedLight(light []byte, strip bool) []byte {
+	if !strip && light != nil {
+		return light
+	}
+	if v.version >= 5 {
+		return nil
+	}
+	return noLight[:]
+}
+
+func (v *slimeVerifier) compareBytes(coord ChunkCoord, what string, expected, actual []byte) {
+	if bytes.Equal(expected, actual) && (expected == nil) == (actual == nil) {
+		return
+	}
+	v.compareArrays(coord, what, len(expected), len(actual), expected == nil, actual == nil, func(i int) string {
+		if expected[i] == actual[i] {
+			return ""
+		}
+		return fmt.Sprintf("%d, expected %d", actual[i], expected[i])
+	})
+}
+
+func (v *slimeVerifier) compareInts(coord ChunkCoord, what string, expected, actual []int) {
+	v.compareArrays(coord, what, len(expected), len(actual), expected == nil, actual == nil, func(i int) string {
+		if expected[i] == actual[i] {
+			return ""
+		}
+		return fmt.Sprintf("%d, expected %d", actual[i], expected[i])
+	})
+}
+
+func (v *slimeVerifier) compareLongs(coord ChunkCoord, what string, expected, actual []int64) {
+	v.compareArrays(coord, what, len(expected), len(actual), expected == nil, actual == nil, func(i int) string {
+		if expected[i] == actual[i] {
+			return ""
+		}
+		return fmt.Sprintf("%d, expected %d", actual[i], expected[i])
+	})
+}
+
+// compareArrays reports arrays that are missing, have the wrong length or have different values, naming the first
+// index that differs. differ describes the values at an index if they are not the same.
+func (v *slimeVerifier) compareArrays(coord ChunkCoord, what string, expectedLen, actualLen int, expectedNil,
+	actualNil bool, differ func(i int) string) {
+	switch {
+	case expectedNil && actualNil:
+		return
+	case actualNil:
+		v.mismatch(coord, "%s is missing", what)
+		return
+	case expectedNil:
+		v.mismatch(coord, "%s was never written", what)
+		return
+	case expectedLen != actualLen:
+		v.mismatch(coord, "%s has length %d, expected %d", what, actualLen, expectedLen)
+		return
+	}
+
+	count, first, firstDifference := 0, 0, ""
+	for i := 0; i < expectedLen; i++ {
+		if difference := differ(i); difference != "" {
+			if count == 0 {
+				first, firstDifference = i, difference
+			}
+			count++
+		}
+	}
+	if count > 0 {
+		v.mismatch(coord, "%s differs at %d of %d indexes, first at %d: %s", what, count, expectedLen, first,
+			firstDifference)
+	}
+}
+
+func (v *slimeVerifier) compareNBT(coord ChunkCoord, what string, expected, actual NBTCompound) {
+	var differences []NBTDifference
+	diffNBT("", map[string]interface{}(expected), map[string]interface{}(actual), &differences)
+	for _, difference := range differences {
+		switch {
+		case difference.To == "":
+			v.mismatch(coord, "%s is missing %s", what, difference.Path)
+		case difference.From == "":
+			v.mismatch(coord, "%s has unexpected %s: %s", what, difference.Path, difference.To)
+		default:
+			v.mismatch(coord, "%s has %s: %s, expected %s", what, difference.Path, difference.To, difference.From)
+		}
+	}
+}
+
+// verifyCompounds compares compounds such as tile entities in order. name describes a compound in a mismatch.
+func (v *slimeVerifier) verifyCompounds(coord ChunkCoord, kind, kinds string, name func(NBTCompound) string,
+	expected, actual []NBTCompound) {
+	if len(expected) != len(actual) {
+		v.mismatch(coord, "has %d %s, expected %d", len(actual), kinds, len(expected))
+		return
+	}
+	for i := range expected {
+		v.compareNBT(coord, fmt.Sprintf("%s %d (%s)", kind, i, name(expected[i])), expected[i], actual[i])
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func writeAndReadBack(t *testing.T, world *AnvilWorld, options SlimeWriteOptions) *AnvilWorld {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := world.WriteAsSlime(context.Background(), &buf, options); err != nil {
+		t.Fatal(err)
+	}
+	readBack, err := ReadSlimeWorld(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readBack
+}
+
+func TestVerifySlime(t *testing.T) {
+	for _, options := range []SlimeWriteOptions{{}, {Version: 5, Light: LightStrip}, {Light: LightStrip}} {
+		world := newTestWorld()
+		if err := world.VerifySlime(writeAndReadBack(t, world, options), options); err != nil {
+			t.Errorf("%+v: %v", options, err)
+		}
+	}
+
+	world := newTestWorld()
+	readBack := writeAndReadBack(t, world, SlimeWriteOptions{})
+	chunk := readBack.chunks[ChunkCoord{X: 1, Z: 1}]
+	chunk.Sections[0].Blocks[5] = 7
+	chunk.Sections[0].Blocks[6] = 7
+	chunk.TileEntities[0]["Text1"] = "goodbye"
+	chunk.Entities = nil
+	readBack.chunks[ChunkCoord{X: 1, Z: 1}] = chunk
+	delete(readBack.chunks, ChunkCoord{X: -1, Z: -1})
+
+	err := world.VerifySlime(readBack, SlimeWriteOptions{})
+	if !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+	for _, expected := range []string{
+		"(4 mismatches)",
+		"chunk -1,-1: chunk is missing",
+		"chunk 1,1: section 0 blocks differs at 2 of 4096 indexes, first at 5: 7, expected 1",
+		`chunk 1,1: tile entity 0 (minecraft:sign) has Text1: "goodbye", expected "hello"`,
+		"chunk 1,1: has 0 entities, expected 1",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected the report to contain %q:\n%s", expected, err)
+		}
+	}
+}